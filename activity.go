@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const activityRefreshInterval = 2 * time.Second
+
+// activityGrouping orders the activity view so backends of the same user
+// or application are listed together.
+type activityGrouping int
+
+const (
+	groupByNone activityGrouping = iota
+	groupByUser
+	groupByApplication
+)
+
+var activityGroupingNames = map[activityGrouping]string{
+	groupByNone:        "none",
+	groupByUser:        "user",
+	groupByApplication: "application",
+}
+
+var queryPaneStyle = lipgloss.NewStyle().
+	Border(lipgloss.NormalBorder(), false, false, false, true).
+	BorderForeground(lipgloss.Color("241")).
+	PaddingLeft(1)
+
+// Each refresh cycle carries the generation of the view it belongs to, so
+// leaving and reopening the view doesn't start a second cycle.
+type activityTickMsg struct{ generation int }
+type activityMsg struct {
+	generation int
+	backends   []db.Backend
+}
+
+func fetchActivity(conn *pgxpool.Pool, generation int) tea.Cmd {
+	return func() tea.Msg {
+		backends, err := db.GetActivity(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return activityMsg{generation: generation, backends: backends}
+	}
+}
+
+func scheduleActivityRefresh(generation int) tea.Cmd {
+	return tea.Tick(activityRefreshInterval, func(time.Time) tea.Msg {
+		return activityTickMsg{generation: generation}
+	})
+}
+
+func (m *Model) openActivity() tea.Cmd {
+	m.activityGeneration++
+	m.backends = nil
+	m.initActivityTable()
+	m.err = nil
+	m.state = StateActivity
+	return fetchActivity(m.dbConn, m.activityGeneration)
+}
+
+// formatDuration shortens a duration to what is useful at a glance, e.g.
+// 850ms, 12.4s or 1h02m.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func (m *Model) activityGroupKey(b db.Backend) string {
+	switch m.activityGrouping {
+	case groupByUser:
+		return b.User
+	case groupByApplication:
+		return b.Application
+	}
+	return ""
+}
+
+// visibleBackends applies the idle filter and grouping.
+func (m *Model) visibleBackends() []db.Backend {
+	var backends []db.Backend
+	for _, b := range m.backends {
+		if m.hideIdle && b.State == "idle" {
+			continue
+		}
+		backends = append(backends, b)
+	}
+	sort.SliceStable(backends, func(i, j int) bool {
+		return m.activityGroupKey(backends[i]) < m.activityGroupKey(backends[j])
+	})
+	return backends
+}
+
+func (m *Model) activityPaneWidth() int {
+	return max((m.windowSize.Width-4)*2/5, 30)
+}
+
+func (m *Model) initActivityTable() {
+	columns := []table.Column{
+		{Title: "PID", Width: 7},
+		{Title: "User", Width: 12},
+		{Title: "Database", Width: 12},
+		{Title: "Application", Width: 14},
+		{Title: "Client", Width: 15},
+		{Title: "State", Width: 19},
+		{Title: "Wait", Width: 18},
+		{Title: "Started", Width: 8},
+		{Title: "Duration", Width: 8},
+	}
+
+	selected, hadSelection := m.selectedBackend()
+	m.visibleActivity = m.visibleBackends()
+	rows := make([]table.Row, len(m.visibleActivity))
+	for i, b := range m.visibleActivity {
+		started, duration := "", ""
+		if !b.QueryStart.IsZero() {
+			started = b.QueryStart.In(displayLocation).Format("15:04:05")
+			duration = formatDuration(b.Duration)
+		}
+		rows[i] = table.Row{
+			fmt.Sprintf("%d", b.PID),
+			b.User,
+			b.Database,
+			b.Application,
+			b.ClientAddr,
+			b.State,
+			b.WaitEvent,
+			started,
+			duration,
+		}
+	}
+
+	cursor := m.activityTable.Cursor()
+	m.activityTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-12),
+		table.WithWidth(m.windowSize.Width-4-m.activityPaneWidth()),
+	)
+	m.activityTable.SetStyles(tableStyle)
+	m.activityTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+	// Follow the selected backend as rows come and go, so a refresh doesn't
+	// move the selection to another one
+	if hadSelection {
+		for i, b := range m.visibleActivity {
+			if b.PID == selected.PID {
+				m.activityTable.SetCursor(i)
+				break
+			}
+		}
+	}
+}
+
+func (m *Model) selectedBackend() (db.Backend, bool) {
+	if len(m.visibleActivity) == 0 {
+		return db.Backend{}, false
+	}
+	return m.visibleActivity[m.activityTable.Cursor()], true
+}
+
+func (m *Model) updateActivity(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.activityTable, cmd = m.activityTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case activityMsg:
+		if msg.generation != m.activityGeneration {
+			break
+		}
+		m.backends = msg.backends
+		m.initActivityTable()
+		cmds = append(cmds, scheduleActivityRefresh(msg.generation))
+	case activityTickMsg:
+		if msg.generation == m.activityGeneration {
+			cmds = append(cmds, fetchActivity(m.dbConn, msg.generation))
+		}
+	case ddlExecutedMsg:
+		cmds = append(cmds, m.openActivity())
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "i":
+			m.hideIdle = !m.hideIdle
+			m.initActivityTable()
+		case "s":
+			m.activityGrouping = (m.activityGrouping + 1) % activityGrouping(len(activityGroupingNames))
+			m.initActivityTable()
+		case "c":
+			if b, ok := m.selectedBackend(); ok {
+				m.confirmDDL(db.CancelBackendSQL(b.PID), StateActivity)
+			}
+		case "x":
+			if b, ok := m.selectedBackend(); ok {
+				m.confirmDDL(db.TerminateBackendSQL(b.PID), StateActivity)
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+// activitySummary counts the backends per group, or per state without
+// grouping.
+func (m *Model) activitySummary() string {
+	counts := map[string]int{}
+	var keys []string
+	for _, b := range m.visibleActivity {
+		key := m.activityGroupKey(b)
+		if m.activityGrouping == groupByNone {
+			key = b.State
+		}
+		if key == "" {
+			key = "(none)"
+		}
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", key, counts[key])
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (m *Model) activityView() string {
+	filter := "showing idle"
+	if m.hideIdle {
+		filter = "hiding idle"
+	}
+	status := fmt.Sprintf("%d backends, %s, grouped by %s    %s",
+		len(m.visibleActivity), filter, activityGroupingNames[m.activityGrouping], detailTypeStyle.Render(m.activitySummary()))
+
+	query := ""
+	if b, ok := m.selectedBackend(); ok {
+		query = fmt.Sprintf("%s %d\n\n%s", detailNameStyle.Render("PID"), b.PID, b.Query)
+	}
+	pane := queryPaneStyle.Width(m.activityPaneWidth()).Height(m.windowSize.Height - 12).Render(query)
+
+	return status + "\n\n" + lipgloss.JoinHorizontal(lipgloss.Top, m.activityTable.View(), pane)
+}
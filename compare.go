@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// compareSchema is the schema compared, the one the rest of the browser
+// shows.
+const compareSchema = "public"
+
+// compareTarget is a database to compare the current one with: the
+// connection of another tab, or a database on the server opened as the
+// current user for the comparison only.
+type compareTarget struct {
+	title    string
+	label    string
+	conn     *pgxpool.Pool
+	database string
+}
+
+var diffChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("180"))
+
+type schemaSnapshotsMsg struct{ from, to []db.SchemaObject }
+
+func compareSchemas(conn *pgxpool.Pool, target compareTarget, user, password string) tea.Cmd {
+	return func() tea.Msg {
+		other := target.conn
+		if other == nil {
+			var err error
+			other, err = db.ConnectAsUser(user, password, target.database, sessionSettings)
+			if err != nil {
+				return errMsg{err: err}
+			}
+			defer other.Close()
+		}
+
+		from, err := db.GetSchemaSnapshot(conn, compareSchema)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		to, err := db.GetSchemaSnapshot(other, compareSchema)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return schemaSnapshotsMsg{from: from, to: to}
+	}
+}
+
+// openCompare lists what the current database can be compared with.
+func (m *Model) openCompare() {
+	m.compareTargets = nil
+	for i, s := range m.sessions {
+		if s != m.Session && s.dbConn != nil {
+			m.compareTargets = append(m.compareTargets, compareTarget{
+				title: fmt.Sprintf("Tab %d: %s@%s", i+1, s.selectedUser, s.selectedDB),
+				label: s.selectedUser + "@" + s.selectedDB,
+				conn:  s.dbConn,
+			})
+		}
+	}
+	for _, d := range m.databases {
+		if d.Name != m.selectedDB {
+			m.compareTargets = append(m.compareTargets, compareTarget{
+				title:    "Database " + d.Name,
+				label:    m.selectedUser + "@" + d.Name,
+				database: d.Name,
+			})
+		}
+	}
+
+	titles := make([]string, len(m.compareTargets))
+	for i, t := range m.compareTargets {
+		titles[i] = t.title
+	}
+	m.compareList = m.newPickerList("Compare "+m.selectedDB+" with", titles)
+	m.err = nil
+	m.state = StateCompareTarget
+}
+
+func (m *Model) updateCompareTarget(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.compareList, cmd = m.compareList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "enter":
+			if len(m.compareTargets) == 0 {
+				break
+			}
+			target := m.compareTargets[m.compareList.Index()]
+			m.schemaDiffFrom = m.selectedUser + "@" + m.selectedDB
+			m.schemaDiffTo = target.label
+			m.schemaSnapshots = [2][]db.SchemaObject{}
+			m.schemaChanges = nil
+			m.schemaDiffRunning = true
+			m.initSchemaDiffTable()
+			m.state = StateSchemaDiff
+			cmds = append(cmds, compareSchemas(m.dbConn, target, m.selectedUser, m.userPassword))
+		}
+	}
+
+	return cmds
+}
+
+// schemaDiffHeights splits the height of the view between the list of
+// changes and the definition panes under it.
+func (m *Model) schemaDiffHeights() (int, int) {
+	listHeight := m.windowSize.Height - 12
+	tableHeight := max(listHeight/3, 5)
+	return tableHeight, max(listHeight-tableHeight-3, 3)
+}
+
+func (m *Model) initSchemaDiffTable() {
+	columns := []table.Column{
+		{Title: "Change", Width: 9},
+		{Title: "Kind", Width: 10},
+		{Title: "Name", Width: max(m.windowSize.Width-4-19-6, 20)},
+	}
+
+	rows := make([]table.Row, len(m.schemaChanges))
+	for i, c := range m.schemaChanges {
+		rows[i] = table.Row{c.Change, c.Kind, c.Name}
+	}
+
+	tableHeight, _ := m.schemaDiffHeights()
+	cursor := m.schemaDiffTable.Cursor()
+	m.schemaDiffTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(tableHeight),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.schemaDiffTable.SetStyles(tableStyle)
+	m.schemaDiffTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+	m.showSchemaChange()
+}
+
+// showSchemaChange puts both sides of the selected change in the panes.
+func (m *Model) showSchemaChange() {
+	from, to := "", ""
+	if len(m.schemaChanges) > 0 {
+		c := m.schemaChanges[m.schemaDiffTable.Cursor()]
+		from, to = c.From.Definition, c.To.Definition
+	}
+	m.schemaFromView.SetContent(highlightSQL(from))
+	m.schemaToView.SetContent(highlightSQL(to))
+	m.schemaFromView.GotoTop()
+	m.schemaToView.GotoTop()
+}
+
+// diffSchemas compares the snapshots in the current direction.
+func (m *Model) diffSchemas() {
+	m.schemaChanges = db.DiffSchemas(m.schemaSnapshots[0], m.schemaSnapshots[1])
+	m.initSchemaDiffTable()
+}
+
+func (m *Model) migrationScript() string {
+	return db.MigrationScript(m.schemaDiffFrom, m.schemaDiffTo, m.schemaChanges)
+}
+
+func (m *Model) updateSchemaDiff(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	cursor := m.schemaDiffTable.Cursor()
+	m.schemaDiffTable, cmd = m.schemaDiffTable.Update(msg)
+	cmds = append(cmds, cmd)
+	if m.schemaDiffTable.Cursor() != cursor {
+		m.showSchemaChange()
+	}
+
+	switch msg := msg.(type) {
+	case schemaSnapshotsMsg:
+		m.schemaDiffRunning = false
+		m.schemaSnapshots = [2][]db.SchemaObject{msg.from, msg.to}
+		m.diffSchemas()
+	case tea.KeyMsg:
+		if m.schemaDiffRunning {
+			if msg.String() == "esc" {
+				m.state = StateListTables
+			}
+			break
+		}
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "J":
+			m.schemaFromView.LineDown(1)
+			m.schemaToView.LineDown(1)
+		case "K":
+			m.schemaFromView.LineUp(1)
+			m.schemaToView.LineUp(1)
+		case "r":
+			m.schemaDiffFrom, m.schemaDiffTo = m.schemaDiffTo, m.schemaDiffFrom
+			m.schemaSnapshots[0], m.schemaSnapshots[1] = m.schemaSnapshots[1], m.schemaSnapshots[0]
+			m.diffSchemas()
+		case "m":
+			m.showDDL(fmt.Sprintf("Migration from %s to %s", m.schemaDiffFrom, m.schemaDiffTo),
+				highlightSQL(m.migrationScript()), StateSchemaDiff)
+		case "w":
+			m.openForm(formSaveMigration, fmt.Sprintf("Save the migration from %s to %s", m.schemaDiffFrom, m.schemaDiffTo), StateSchemaDiff,
+				formField{prompt: "File", placeholder: "migration.sql"})
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+// saveMigrationWithFormParams writes the migration script to the file from
+// the save form and shows what was written.
+func (m *Model) saveMigrationWithFormParams() tea.Cmd {
+	path := m.formValue(0)
+	if path == "" {
+		path = "migration.sql"
+	}
+	m.showDDL(fmt.Sprintf("Saving the migration to %s", path), "Saving...", StateSchemaDiff)
+	return writeDDL(m.migrationScript(), path)
+}
+
+func (m *Model) schemaDiffView() string {
+	if m.schemaDiffRunning {
+		return fmt.Sprintf("Comparing %s with %s...", m.schemaDiffFrom, m.schemaDiffTo)
+	}
+
+	counts := map[string]int{}
+	for _, c := range m.schemaChanges {
+		counts[c.Change]++
+	}
+	// Recreated views come with the changes that cause them
+	counts[db.ChangeChanged] += counts[db.ChangeRecreated]
+	status := fmt.Sprintf("%s in %s → %s    %s, %s, %s", compareSchema,
+		selectedStyle.Render(m.schemaDiffFrom), selectedStyle.Render(m.schemaDiffTo),
+		diffAddedStyle.Render(fmt.Sprintf("%d added", counts[db.ChangeAdded])),
+		diffRemovedStyle.Render(fmt.Sprintf("%d removed", counts[db.ChangeRemoved])),
+		diffChangedStyle.Render(fmt.Sprintf("%d changed", counts[db.ChangeChanged])))
+	if len(m.schemaChanges) == 0 {
+		return status + "\n\nThe schemas are the same."
+	}
+
+	paneWidth := (m.windowSize.Width - 4) / 2
+	pane := func(title string, content string) string {
+		return queryPaneStyle.Width(paneWidth - 1).Render(detailNameStyle.Render(title) + "\n" + content)
+	}
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		pane(m.schemaDiffFrom, m.schemaFromView.View()),
+		pane(m.schemaDiffTo, m.schemaToView.View()))
+	return status + "\n\n" + m.schemaDiffTable.View() + "\n" + panes
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"time"
+
+	"lazysql/db"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	// healthCheckInterval is how often an open session pings its server
+	healthCheckInterval = 10 * time.Second
+	maxReconnectDelay   = 30 * time.Second
+)
+
+var reconnectingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+
+// sessionSettings are set with flags and applied to every connection to the
+// selected database.
+var sessionSettings db.SessionSettings
+
+type healthCheckMsg struct {
+	conn *pgxpool.Pool
+	err  error
+}
+
+func checkHealth(conn *pgxpool.Pool, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return healthCheckMsg{conn: conn, err: db.Ping(conn)}
+	})
+}
+
+// reconnectDelay doubles from one second up to maxReconnectDelay.
+func reconnectDelay(attempt int) time.Duration {
+	return min(time.Second<<min(attempt-1, 5), maxReconnectDelay)
+}
+
+// handleHealthCheck schedules the next check: soon after a failure, while
+// the pool keeps trying to open a new connection, and at the regular
+// interval once it works again.
+func (m *Model) handleHealthCheck(msg healthCheckMsg) tea.Cmd {
+	// The connection was closed since, which ends its chain of checks
+	if msg.conn != m.dbConn {
+		return nil
+	}
+	if msg.err != nil {
+		m.reconnectAttempt++
+		return checkHealth(m.dbConn, reconnectDelay(m.reconnectAttempt))
+	}
+	m.reconnectAttempt = 0
+	return checkHealth(m.dbConn, healthCheckInterval)
+}
+
+// closeDBConn closes the connection to the selected database and forgets
+// everything that was loaded over it.
+func (m *Model) closeDBConn() {
+	if m.dbConn != nil {
+		m.dbConn.Close()
+		m.dbConn = nil
+	}
+	m.reconnectAttempt = 0
+
+	m.tables = nil
+	m.tableList.SetItems(nil)
+	m.selectedTable = ""
+	m.gridTable = ""
+	m.navStack = nil
+	m.restoreLocation = nil
+	m.err = nil
+}
+
+// selectItem moves a list's cursor to the item titled title, if it is there.
+func selectItem(items []string, title string, selectFn func(int)) {
+	if i := indexOf(items, title); i >= 0 {
+		selectFn(i)
+	}
+}
+
+// switchDatabase goes back to the database list as the same user. The
+// password is remembered, so picking a database connects right away.
+func (m *Model) switchDatabase() tea.Cmd {
+	previous := m.selectedDB
+	m.closeDBConn()
+	m.selectedDB = ""
+	m.state = StateSelectDatabase
+	selectItem(m.databaseNames(), previous, m.databaseList.Select)
+	return fetchDatabases(m.conn)
+}
+
+// switchUser goes back to the user list with the current user selected.
+func (m *Model) switchUser() tea.Cmd {
+	previousDB := m.selectedDB
+	m.closeDBConn()
+	m.userPassword = ""
+	m.state = StateSelectUser
+	selectItem(m.userNames(), m.selectedUser, m.userList.Select)
+	selectItem(m.databaseNames(), previousDB, m.databaseList.Select)
+	m.selectedDB = ""
+	return nil
+}
+
+// recoveryState is where StateError returns to: the deepest state whose
+// connection is still open.
+func (m *Model) recoveryState() State {
+	switch {
+	case m.dbConn != nil:
+		return StateListTables
+	case m.selectedUser != "":
+		return StateSelectDatabase
+	}
+	return StateSelectUser
+}
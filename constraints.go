@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type constraintsMsg struct {
+	constraints []db.Constraint
+	columns     []db.Column
+}
+type fkRefColumnsMsg struct{ columns []db.Column }
+
+func fetchConstraints(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		constraints, err := db.GetConstraints(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		columns, err := db.GetColumnInfo(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return constraintsMsg{constraints: constraints, columns: columns}
+	}
+}
+
+func fetchFKRefColumns(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		columns, err := db.GetColumnInfo(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return fkRefColumnsMsg{columns: columns}
+	}
+}
+
+func (m *Model) initConstraintTable() {
+	columns := []table.Column{
+		{Title: "Constraint", Width: 30},
+		{Title: "Type", Width: 12},
+		{Title: "Validated", Width: 9},
+		{Title: "Definition", Width: max(m.windowSize.Width-4-51-8, 30)},
+	}
+
+	rows := make([]table.Row, len(m.constraints))
+	for i, c := range m.constraints {
+		validated := "yes"
+		if !c.Validated {
+			validated = "no"
+		}
+		rows[i] = table.Row{c.Name, c.Type, validated, c.Definition}
+	}
+
+	cursor := m.constraintTable.Cursor()
+	m.constraintTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.constraintTable.SetStyles(tableStyle)
+	m.constraintTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateConstraints(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.constraintTable, cmd = m.constraintTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case constraintsMsg:
+		m.constraints = msg.constraints
+		m.constraintColumns = msg.columns
+		m.initConstraintTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchConstraints(m.dbConn, m.selectedTable))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "c":
+			m.openForm(formAddCheck, fmt.Sprintf("Add Check Constraint on %s", m.selectedTable), StateConstraints,
+				formField{prompt: "Name", placeholder: "optional, generated when empty"},
+				formField{prompt: "Check", placeholder: "e.g. price > 0"},
+				formField{prompt: "Not valid", options: yesNo},
+			)
+		case "F":
+			m.fkTableList = m.newPickerList("Referenced Table", m.tables)
+			m.state = StateForeignKeyTable
+		}
+
+		if len(m.constraints) == 0 {
+			break
+		}
+		c := m.constraints[m.constraintTable.Cursor()]
+		switch msg.String() {
+		case "x":
+			m.confirmDDL(db.DropConstraintDDL(m.selectedTable, c.Name, false), StateConstraints)
+		case "v":
+			if !c.Validated {
+				m.confirmDDL(db.ValidateConstraintDDL(m.selectedTable, c.Name), StateConstraints)
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) updateForeignKeyTable(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.fkTableList, cmd = m.fkTableList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateConstraints
+		case "enter":
+			selectedItem := m.fkTableList.SelectedItem()
+			if selectedItem != nil {
+				m.fkRefTable = selectedItem.(myListItem).title
+				cmds = append(cmds, fetchFKRefColumns(m.dbConn, m.fkRefTable))
+				// Transition to StateForeignKeyColumns happens after columns are fetched
+			}
+		}
+	case fkRefColumnsMsg:
+		m.fkRefColumns = msg.columns
+		m.fkSelected = make([]bool, len(msg.columns))
+		// Most foreign keys reference the primary key, so start with it selected
+		for i, col := range msg.columns {
+			m.fkSelected[i] = col.PrimaryKey
+		}
+		m.fkColumnList = m.newPickerList(fmt.Sprintf("Referenced Columns in %s", m.fkRefTable), nil)
+		m.refreshFKColumnList()
+		m.state = StateForeignKeyColumns
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) refreshFKColumnList() {
+	items := make([]list.Item, len(m.fkRefColumns))
+	for i, col := range m.fkRefColumns {
+		items[i] = myListItem{title: fmt.Sprintf("%s %s  %s", checkbox(m.fkSelected[i]), col.Name, col.Type)}
+	}
+	m.fkColumnList.SetItems(items)
+}
+
+func (m *Model) selectedFKRefColumns() []string {
+	var names []string
+	for i, col := range m.fkRefColumns {
+		if m.fkSelected[i] {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+func (m *Model) updateForeignKeyColumns(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.fkColumnList, cmd = m.fkColumnList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return cmds
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		m.state = StateForeignKeyTable
+	case " ":
+		if len(m.fkRefColumns) == 0 {
+			break
+		}
+		i := m.fkColumnList.Index()
+		m.fkSelected[i] = !m.fkSelected[i]
+		m.refreshFKColumnList()
+	case "enter":
+		if len(m.fkRefColumns) == 0 {
+			break
+		}
+		refColumns := m.selectedFKRefColumns()
+		if len(refColumns) == 0 {
+			m.fkSelected[m.fkColumnList.Index()] = true
+			refColumns = m.selectedFKRefColumns()
+		}
+
+		// A single referenced column gets a picker over this table's columns,
+		// composite keys are typed as a list in the same order.
+		localColumns := formField{prompt: "Columns", placeholder: fmt.Sprintf("%d comma separated columns", len(refColumns))}
+		if len(refColumns) == 1 {
+			var names []string
+			for _, col := range m.constraintColumns {
+				names = append(names, col.Name)
+			}
+			localColumns = formField{prompt: "Column", options: names, value: guessFKColumn(names, m.fkRefTable, refColumns[0])}
+		}
+
+		m.openForm(formAddForeignKey,
+			fmt.Sprintf("Foreign Key from %s to %s (%s)", m.selectedTable, m.fkRefTable, strings.Join(refColumns, ", ")),
+			StateConstraints,
+			formField{prompt: "Name", placeholder: "optional, generated when empty"},
+			localColumns,
+			formField{prompt: "On delete", options: db.ReferentialActions},
+			formField{prompt: "On update", options: db.ReferentialActions},
+			formField{prompt: "Not valid", options: yesNo},
+		)
+	}
+
+	return cmds
+}
+
+// guessFKColumn picks the local column most likely to hold the reference,
+// e.g. user_id or users_id for users(id).
+func guessFKColumn(names []string, refTable, refColumn string) string {
+	candidates := []string{
+		strings.TrimSuffix(refTable, "s") + "_" + refColumn,
+		refTable + "_" + refColumn,
+		refColumn,
+	}
+	for _, candidate := range candidates {
+		for _, name := range names {
+			if name == candidate {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// databaseItems lists the databases with a summary of each next to its name.
+func databaseItems(databases []db.Database) []list.Item {
+	items := make([]list.Item, len(databases))
+	for i, d := range databases {
+		details := []string{"owner " + d.Owner, d.Encoding, d.Collation}
+		if d.Size != "" {
+			details = append(details, d.Size)
+		}
+		details = append(details, fmt.Sprintf("%d connections", d.Connections))
+		items[i] = myListItem{title: d.Name, desc: strings.Join(details, " · ")}
+	}
+	return items
+}
+
+func (m *Model) selectedDatabase() (db.Database, bool) {
+	if len(m.databases) == 0 {
+		return db.Database{}, false
+	}
+	return m.databases[m.databaseList.Index()], true
+}
+
+func (m *Model) databaseNames() []string {
+	names := make([]string, len(m.databases))
+	for i, d := range m.databases {
+		names[i] = d.Name
+	}
+	return names
+}
+
+func (m *Model) userNames() []string {
+	var names []string
+	for _, item := range m.userList.Items() {
+		names = append(names, item.(myListItem).title)
+	}
+	return names
+}
+
+// updateDatabaseActions handles creating, renaming and dropping databases
+// from the database list. They run over the maintenance connection.
+func (m *Model) updateDatabaseActions(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case databasesMsg:
+		m.databases = msg.databases
+		m.databaseList.SetItems(databaseItems(msg.databases))
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchDatabases(m.conn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "n":
+			m.openForm(formCreateDatabase, "Create Database", StateSelectDatabase,
+				formField{prompt: "Name"},
+				formField{prompt: "Owner", options: m.userNames(), value: m.selectedUser},
+				formField{prompt: "Template", placeholder: "optional, template1 by default"},
+				formField{prompt: "Encoding", placeholder: "optional, e.g. UTF8"},
+				formField{prompt: "Locale", placeholder: "optional, e.g. en_US.UTF-8 (needs template0)"},
+				formField{prompt: "Tablespace", placeholder: "optional"},
+			)
+		case "R":
+			if d, ok := m.selectedDatabase(); ok {
+				m.openForm(formRenameDatabase, fmt.Sprintf("Rename Database %s", d.Name), StateSelectDatabase,
+					formField{prompt: "New name", value: d.Name},
+				)
+			}
+		case "x":
+			if d, ok := m.selectedDatabase(); ok {
+				m.openForm(formDropDatabase, fmt.Sprintf("Drop Database %s (%d active connections)", d.Name, d.Connections), StateSelectDatabase,
+					formField{prompt: "Terminate active sessions", options: yesNo},
+					formField{prompt: "Type the database name to confirm", placeholder: d.Name},
+				)
+			}
+		}
+	}
+
+	return cmds
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Backend is a client connection as seen in pg_stat_activity.
+type Backend struct {
+	PID         int
+	User        string
+	Database    string
+	Application string
+	ClientAddr  string
+	State       string
+	WaitEvent   string
+	// QueryStart is zero for backends that never ran a query
+	QueryStart time.Time
+	Duration   time.Duration
+	Query      string
+}
+
+func GetActivity(conn *pgxpool.Pool) ([]Backend, error) {
+	sql := `SELECT pid, COALESCE(usename, ''), COALESCE(datname, ''), application_name,
+			COALESCE(host(client_addr), 'local'), COALESCE(state, ''),
+			COALESCE(wait_event_type || ': ' || wait_event, ''),
+			query_start, COALESCE(extract(epoch FROM now() - query_start), 0)::float8, query
+		FROM pg_stat_activity
+		WHERE backend_type = 'client backend' AND pid <> pg_backend_pid()
+		ORDER BY query_start NULLS LAST, pid`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching activity: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backends []Backend
+	for rows.Next() {
+		var b Backend
+		var queryStart *time.Time
+		var seconds float64
+		if err := rows.Scan(&b.PID, &b.User, &b.Database, &b.Application, &b.ClientAddr, &b.State,
+			&b.WaitEvent, &queryStart, &seconds, &b.Query); err != nil {
+			log.Printf("Error scanning activity: %v", err)
+			return nil, err
+		}
+		if queryStart != nil {
+			b.QueryStart = *queryStart
+		}
+		b.Duration = time.Duration(seconds * float64(time.Second))
+		backends = append(backends, b)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return backends, nil
+}
+
+// CancelBackendSQL cancels the query a backend is running.
+func CancelBackendSQL(pid int) string {
+	return fmt.Sprintf("SELECT pg_cancel_backend(%d)", pid)
+}
+
+// TerminateBackendSQL closes a backend's connection.
+func TerminateBackendSQL(pid int) string {
+	return fmt.Sprintf("SELECT pg_terminate_backend(%d)", pid)
+}
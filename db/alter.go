@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// typeNamePattern accepts type names as written in DDL, e.g. "integer",
+// "character varying(20)", "numeric(10, 2)[]" or "public.my_enum".
+var typeNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .]*(\(\d+(,\s*\d+)?\))?( with(out)? time zone)?(\[\])*$`)
+
+func validateTypeName(typeName string) error {
+	if !typeNamePattern.MatchString(typeName) {
+		return fmt.Errorf("invalid type %q", typeName)
+	}
+	return nil
+}
+
+// quoteLiteral quotes a string as a SQL literal, doubling embedded quotes.
+func quoteLiteral(s string) string {
+	literal := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.Contains(s, `\`) {
+		return "E" + strings.ReplaceAll(literal, `\`, `\\`)
+	}
+	return literal
+}
+
+func alterTable(tableName string) string {
+	return "ALTER TABLE " + pgx.Identifier{tableName}.Sanitize()
+}
+
+func AddColumnDDL(tableName, columnName, typeName string, notNull bool, defaultExpr string) (string, error) {
+	if columnName == "" {
+		return "", fmt.Errorf("column name cannot be empty")
+	}
+	if err := validateTypeName(typeName); err != nil {
+		return "", err
+	}
+
+	ddl := fmt.Sprintf("%s ADD COLUMN %s %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize(), typeName)
+	if notNull {
+		ddl += " NOT NULL"
+	}
+	if defaultExpr != "" {
+		if err := validateExpression(defaultExpr); err != nil {
+			return "", fmt.Errorf("default: %w", err)
+		}
+		ddl += " DEFAULT " + defaultExpr
+	}
+	return ddl, nil
+}
+
+func DropColumnDDL(tableName, columnName string, cascade bool) string {
+	ddl := fmt.Sprintf("%s DROP COLUMN %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize())
+	if cascade {
+		ddl += " CASCADE"
+	}
+	return ddl
+}
+
+func RenameColumnDDL(tableName, columnName, newName string) (string, error) {
+	if newName == "" {
+		return "", fmt.Errorf("new column name cannot be empty")
+	}
+	return fmt.Sprintf("%s RENAME COLUMN %s TO %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize(), pgx.Identifier{newName}.Sanitize()), nil
+}
+
+// AlterColumnTypeDDL changes a column's type. usingExpr converts existing
+// values when there is no implicit cast, e.g. "price::numeric".
+func AlterColumnTypeDDL(tableName, columnName, typeName, usingExpr string) (string, error) {
+	if err := validateTypeName(typeName); err != nil {
+		return "", err
+	}
+
+	ddl := fmt.Sprintf("%s ALTER COLUMN %s TYPE %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize(), typeName)
+	if usingExpr != "" {
+		if err := validateExpression(usingExpr); err != nil {
+			return "", fmt.Errorf("USING: %w", err)
+		}
+		ddl += " USING " + usingExpr
+	}
+	return ddl, nil
+}
+
+func SetNotNullDDL(tableName, columnName string, notNull bool) string {
+	action := "DROP NOT NULL"
+	if notNull {
+		action = "SET NOT NULL"
+	}
+	return fmt.Sprintf("%s ALTER COLUMN %s %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize(), action)
+}
+
+// SetDefaultDDL sets a column default, or drops it when defaultExpr is empty.
+func SetDefaultDDL(tableName, columnName, defaultExpr string) (string, error) {
+	action := "DROP DEFAULT"
+	if defaultExpr != "" {
+		if err := validateExpression(defaultExpr); err != nil {
+			return "", fmt.Errorf("default: %w", err)
+		}
+		action = "SET DEFAULT " + defaultExpr
+	}
+	return fmt.Sprintf("%s ALTER COLUMN %s %s", alterTable(tableName), pgx.Identifier{columnName}.Sanitize(), action), nil
+}
+
+// ColumnCommentDDL sets a column comment, or removes it when comment is empty.
+func ColumnCommentDDL(tableName, columnName, comment string) string {
+	value := "NULL"
+	if comment != "" {
+		value = quoteLiteral(comment)
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %s IS %s", pgx.Identifier{tableName, columnName}.Sanitize(), value)
+}
+
+func RenameTableDDL(tableName, newName string) (string, error) {
+	if newName == "" {
+		return "", fmt.Errorf("new table name cannot be empty")
+	}
+	return fmt.Sprintf("%s RENAME TO %s", alterTable(tableName), pgx.Identifier{newName}.Sanitize()), nil
+}
+
+// ExecDDL runs a statement previously built and shown to the user.
+func ExecDDL(conn *pgxpool.Pool, ddl string) error {
+	_, err := conn.Exec(context.Background(), ddl)
+
+	if err != nil {
+		log.Printf("Error executing DDL: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully executed: %v", ddl)
+	return nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import "testing"
+
+func TestSetDefaultDDL(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{expr: "", want: `ALTER TABLE "t" ALTER COLUMN "c" DROP DEFAULT`},
+		{expr: "now()", want: `ALTER TABLE "t" ALTER COLUMN "c" SET DEFAULT now()`},
+		{expr: "'a;b'", want: `ALTER TABLE "t" ALTER COLUMN "c" SET DEFAULT 'a;b'`},
+		{expr: "'it''s'", want: `ALTER TABLE "t" ALTER COLUMN "c" SET DEFAULT 'it''s'`},
+		{expr: "0; DROP TABLE x", wantErr: true},
+		{expr: "0 -- comment", wantErr: true},
+		{expr: "$$x$$", wantErr: true},
+		{expr: "lower('x'", wantErr: true},
+		{expr: "'x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SetDefaultDDL("t", "c", tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SetDefaultDDL(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SetDefaultDDL(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestAlterColumnTypeDDLUsing(t *testing.T) {
+	if _, err := AlterColumnTypeDDL("t", "c", "integer", "c::integer; DROP TABLE x"); err == nil {
+		t.Error("AlterColumnTypeDDL() accepted a second statement in USING")
+	}
+	if _, err := AddColumnDDL("t", "c", "integer", false, "1); DROP TABLE x; --"); err == nil {
+		t.Error("AddColumnDDL() accepted a second statement in the default")
+	}
+}
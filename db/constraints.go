@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Constraint struct {
+	Name       string
+	Type       string
+	Definition string
+	Validated  bool
+}
+
+var constraintTypes = map[string]string{
+	"p": "PRIMARY KEY",
+	"u": "UNIQUE",
+	"c": "CHECK",
+	"x": "EXCLUDE",
+	"f": "FOREIGN KEY",
+	"t": "TRIGGER",
+}
+
+func GetConstraints(conn *pgxpool.Pool, tableName string) ([]Constraint, error) {
+	sql := `SELECT conname, contype::text, pg_get_constraintdef(oid), convalidated
+		FROM pg_constraint
+		WHERE conrelid = $1::regclass
+		ORDER BY array_position(ARRAY['p', 'u', 'f', 'c', 'x'], contype::text), conname`
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error querying constraints: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var constraints []Constraint
+	for rows.Next() {
+		var c Constraint
+		var contype string
+		if err := rows.Scan(&c.Name, &contype, &c.Definition, &c.Validated); err != nil {
+			log.Printf("Error while scanning constraint: %v", err)
+			return nil, err
+		}
+		c.Type = constraintTypes[contype]
+		constraints = append(constraints, c)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return constraints, nil
+}
+
+var ReferentialActions = []string{"NO ACTION", "RESTRICT", "CASCADE", "SET NULL", "SET DEFAULT"}
+
+type ForeignKeyDef struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   string
+	OnUpdate   string
+	// NotValid skips checking existing rows; run VALIDATE CONSTRAINT later
+	NotValid bool
+}
+
+func identifierList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = pgx.Identifier{name}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func validReferentialAction(action string) bool {
+	for _, a := range ReferentialActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func AddForeignKeyDDL(tableName string, def ForeignKeyDef) (string, error) {
+	if len(def.Columns) == 0 || len(def.RefColumns) == 0 {
+		return "", fmt.Errorf("a foreign key needs local and referenced columns")
+	}
+	if len(def.Columns) != len(def.RefColumns) {
+		return "", fmt.Errorf("%d local columns cannot reference %d columns", len(def.Columns), len(def.RefColumns))
+	}
+	for _, action := range []string{def.OnDelete, def.OnUpdate} {
+		if !validReferentialAction(action) {
+			return "", fmt.Errorf("unknown referential action %q", action)
+		}
+	}
+
+	ddl := alterTable(tableName) + " ADD "
+	if def.Name != "" {
+		ddl += "CONSTRAINT " + pgx.Identifier{def.Name}.Sanitize() + " "
+	}
+	ddl += fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s ON UPDATE %s",
+		identifierList(def.Columns), pgx.Identifier{def.RefTable}.Sanitize(), identifierList(def.RefColumns), def.OnDelete, def.OnUpdate)
+	if def.NotValid {
+		ddl += " NOT VALID"
+	}
+	return ddl, nil
+}
+
+// AddCheckDDL adds a CHECK constraint. expr is a SQL boolean expression and
+// is used verbatim once validateExpression accepts it.
+func AddCheckDDL(tableName, name, expr string, notValid bool) (string, error) {
+	if expr == "" {
+		return "", fmt.Errorf("check expression cannot be empty")
+	}
+	if err := validateExpression(expr); err != nil {
+		return "", fmt.Errorf("check: %w", err)
+	}
+
+	ddl := alterTable(tableName) + " ADD "
+	if name != "" {
+		ddl += "CONSTRAINT " + pgx.Identifier{name}.Sanitize() + " "
+	}
+	ddl += fmt.Sprintf("CHECK (%s)", expr)
+	if notValid {
+		ddl += " NOT VALID"
+	}
+	return ddl, nil
+}
+
+func DropConstraintDDL(tableName, name string, cascade bool) string {
+	ddl := fmt.Sprintf("%s DROP CONSTRAINT %s", alterTable(tableName), pgx.Identifier{name}.Sanitize())
+	if cascade {
+		ddl += " CASCADE"
+	}
+	return ddl
+}
+
+func ValidateConstraintDDL(tableName, name string) string {
+	return fmt.Sprintf("%s VALIDATE CONSTRAINT %s", alterTable(tableName), pgx.Identifier{name}.Sanitize())
+}
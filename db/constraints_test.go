@@ -0,0 +1,15 @@
+package db
+
+import "testing"
+
+func TestAddCheckDDL(t *testing.T) {
+	got, err := AddCheckDDL("t", "positive", "price > 0", false)
+	if want := `ALTER TABLE "t" ADD CONSTRAINT "positive" CHECK (price > 0)`; err != nil || got != want {
+		t.Errorf("AddCheckDDL() = %q, %v, want %q", got, err, want)
+	}
+	for _, expr := range []string{"true); DROP TABLE x; --", "a > 0) OR (true"} {
+		if _, err := AddCheckDDL("t", "", expr, false); err == nil {
+			t.Errorf("AddCheckDDL(%q) was accepted", expr)
+		}
+	}
+}
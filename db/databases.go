@@ -4,36 +4,100 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-func GetDatabases(conn *pgx.Conn) ([]string, error) {
-	cursor, err := conn.Query(context.Background(), "SELECT datname FROM pg_database WHERE datistemplate = false")
-	defer cursor.Close()
+type Database struct {
+	Name      string
+	Owner     string
+	Encoding  string
+	Collation string
+	// Size is pretty printed, empty when the database can't be connected to
+	Size        string
+	Connections int
+}
 
+func GetDatabases(conn *pgxpool.Pool) ([]Database, error) {
+	sql := `SELECT d.datname, pg_get_userbyid(d.datdba), pg_encoding_to_char(d.encoding), d.datcollate,
+			CASE WHEN has_database_privilege(d.oid, 'CONNECT') THEN pg_size_pretty(pg_database_size(d.oid)) ELSE '' END,
+			(SELECT count(*) FROM pg_stat_activity a WHERE a.datid = d.oid)
+		FROM pg_database d
+		WHERE d.datistemplate = false
+		ORDER BY d.datname`
+	cursor, err := conn.Query(context.Background(), sql)
 	if err != nil {
 		log.Printf("Error querying databses: %v", err)
 		return nil, err
 	}
+	defer cursor.Close()
 
-	var databases []string
+	var databases []Database
 
 	for cursor.Next() {
-		var dbName string
-		if err := cursor.Scan(&dbName); err != nil {
+		var d Database
+		if err := cursor.Scan(&d.Name, &d.Owner, &d.Encoding, &d.Collation, &d.Size, &d.Connections); err != nil {
 			log.Printf("Error while scanning database name: %v", err)
 			return nil, err
 		}
-		databases = append(databases, dbName)
+		databases = append(databases, d)
+	}
+
+	if cursor.Err() != nil {
+		return nil, cursor.Err()
 	}
 
 	return databases, nil
 }
 
-func CreateDatabase(conn *pgx.Conn, dbName string) error {
-	query := fmt.Sprintf("CREATE DATABASE %s", pgx.Identifier{dbName}.Sanitize())
-	_, err := conn.Exec(context.Background(), query)
+// DatabaseDef describes a database to create. Empty options are left to the
+// server, which copies them from the template.
+type DatabaseDef struct {
+	Name       string
+	Owner      string
+	Template   string
+	Encoding   string
+	Locale     string
+	Tablespace string
+}
+
+func CreateDatabaseDDL(def DatabaseDef) (string, error) {
+	if def.Name == "" {
+		return "", fmt.Errorf("database name cannot be empty")
+	}
+
+	var options []string
+	if def.Owner != "" {
+		options = append(options, "OWNER "+pgx.Identifier{def.Owner}.Sanitize())
+	}
+	if def.Template != "" {
+		options = append(options, "TEMPLATE "+pgx.Identifier{def.Template}.Sanitize())
+	}
+	if def.Encoding != "" {
+		options = append(options, "ENCODING "+quoteLiteral(def.Encoding))
+	}
+	if def.Locale != "" {
+		options = append(options, "LOCALE "+quoteLiteral(def.Locale))
+	}
+	if def.Tablespace != "" {
+		options = append(options, "TABLESPACE "+pgx.Identifier{def.Tablespace}.Sanitize())
+	}
+
+	ddl := "CREATE DATABASE " + pgx.Identifier{def.Name}.Sanitize()
+	if len(options) > 0 {
+		ddl += " WITH " + strings.Join(options, " ")
+	}
+	return ddl, nil
+}
+
+func CreateDatabase(conn *pgxpool.Pool, dbName string) error {
+	query, err := CreateDatabaseDDL(DatabaseDef{Name: dbName})
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(context.Background(), query)
 
 	if err != nil {
 		log.Printf("Error creating database: %v", err)
@@ -43,3 +107,20 @@ func CreateDatabase(conn *pgx.Conn, dbName string) error {
 	log.Printf("Successfully created database: %v", dbName)
 	return nil
 }
+
+func RenameDatabaseDDL(dbName, newName string) (string, error) {
+	if newName == "" {
+		return "", fmt.Errorf("new database name cannot be empty")
+	}
+	return fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", pgx.Identifier{dbName}.Sanitize(), pgx.Identifier{newName}.Sanitize()), nil
+}
+
+// DropDatabaseDDL drops a database. With force, the server terminates the
+// sessions connected to it first instead of refusing to drop it.
+func DropDatabaseDDL(dbName string, force bool) string {
+	ddl := "DROP DATABASE " + pgx.Identifier{dbName}.Sanitize()
+	if force {
+		ddl += " WITH (FORCE)"
+	}
+	return ddl
+}
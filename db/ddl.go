@@ -0,0 +1,838 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ddlHeader starts an export the way pg_dump does: bodies aren't checked
+// so functions can come before the tables they use, and names in the
+// script are schema-qualified so no search_path is needed.
+const ddlHeader = `SET check_function_bodies = false;
+SELECT pg_catalog.set_config('search_path', '', false);
+`
+
+// catalogQuerier runs the catalog queries. They run in a transaction with
+// search_path set to pg_catalog, which makes the pg_get_*def functions and
+// format_type qualify every name as pg_dump does.
+type catalogQuerier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func withCatalogSearchPath(conn *pgxpool.Pool, f func(q catalogQuerier) error) error {
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SET LOCAL search_path = pg_catalog"); err != nil {
+		return err
+	}
+	return f(tx)
+}
+
+// queryStrings collects a single text column.
+func queryStrings(q catalogQuerier, sql string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(context.Background(), sql, args...)
+	if err != nil {
+		log.Printf("Error querying the catalog: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func qualifiedName(schema, name string) string {
+	return pgx.Identifier{schema, name}.Sanitize()
+}
+
+// grantStatements compares an ACL with the default one of its object type
+// (the acldefault codes, such as 'r' for relations) like pg_dump does:
+// default privileges that were taken away are revoked first, then the
+// extra ones granted. aclSQL selects the ACL and the owner of object $1.
+func grantStatements(q catalogQuerier, aclSQL string, oid uint32, objType, on string) ([]string, error) {
+	on = strings.ReplaceAll(on, "'", "''")
+	sql := `WITH o AS (SELECT * FROM (` + aclSQL + `) o(acl, owner)),
+			granted AS (SELECT a.grantee, a.privilege_type, a.is_grantable FROM o, aclexplode(o.acl) a),
+			defaults AS (SELECT a.grantee, a.privilege_type, a.is_grantable
+				FROM o, aclexplode(acldefault($2::text::"char", o.owner)) a WHERE o.acl IS NOT NULL)
+		SELECT statement FROM (
+			SELECT 0, 'REVOKE ' || string_agg(r.privilege_type, ', ' ORDER BY r.privilege_type) || ' ON ` + on + ` FROM ' ||
+					CASE WHEN r.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(pg_get_userbyid(r.grantee)) END || ';'
+				FROM (SELECT grantee, privilege_type FROM defaults EXCEPT SELECT grantee, privilege_type FROM granted) r
+				GROUP BY r.grantee
+			UNION ALL
+			SELECT 1, 'GRANT ' || string_agg(g.privilege_type, ', ' ORDER BY g.privilege_type) || ' ON ` + on + ` TO ' ||
+					CASE WHEN g.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(pg_get_userbyid(g.grantee)) END ||
+					CASE WHEN g.is_grantable THEN ' WITH GRANT OPTION' ELSE '' END || ';'
+				FROM (SELECT * FROM granted EXCEPT SELECT * FROM defaults) g
+				GROUP BY g.grantee, g.is_grantable
+		) s(phase, statement)
+		ORDER BY phase, statement`
+	return queryStrings(q, sql, oid, objType)
+}
+
+func ownerStatement(kind, name, owner string) string {
+	return fmt.Sprintf("ALTER %s %s OWNER TO %s;", kind, name, pgx.Identifier{owner}.Sanitize())
+}
+
+func commentStatement(kind, name, comment string) string {
+	return fmt.Sprintf("COMMENT ON %s %s IS %s;", kind, name, quoteLiteral(comment))
+}
+
+// tableDDL holds the statements of a table in the order they must run.
+// Foreign keys are kept apart, an export adds them once every table exists.
+type tableDDL struct {
+	name        string
+	parents     []string
+	statements  []string
+	foreignKeys []string
+}
+
+// tableInfo describes a table. Its name and those of its parents are
+// printed by regclass, so they can be matched against each other.
+type tableInfo struct {
+	oid          uint32
+	name         string
+	relname      string
+	owner        string
+	comment      string
+	partitionOf  string
+	partBound    string
+	partitionKey string
+	parents      []string
+	rowSecurity  bool
+}
+
+func getTableInfos(q catalogQuerier, schema, tableName string) ([]tableInfo, error) {
+	sql := `SELECT c.oid, c.oid::regclass::text, c.relname, pg_get_userbyid(c.relowner), COALESCE(obj_description(c.oid, 'pg_class'), ''),
+			CASE WHEN c.relispartition THEN (SELECT i.inhparent::regclass::text FROM pg_inherits i WHERE i.inhrelid = c.oid) ELSE '' END,
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), ''),
+			CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END,
+			ARRAY(SELECT i.inhparent::regclass::text FROM pg_inherits i WHERE i.inhrelid = c.oid ORDER BY i.inhseqno),
+			c.relrowsecurity
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND ($2::text = '' OR c.relname = $2)
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		ORDER BY c.relname`
+	rows, err := q.Query(context.Background(), sql, schema, tableName)
+	if err != nil {
+		log.Printf("Error fetching tables: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []tableInfo
+	for rows.Next() {
+		var t tableInfo
+		if err := rows.Scan(&t.oid, &t.name, &t.relname, &t.owner, &t.comment, &t.partitionOf, &t.partBound,
+			&t.partitionKey, &t.parents, &t.rowSecurity); err != nil {
+			log.Printf("Error scanning tables: %v", err)
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// createStatement creates the table with the given column definitions. A
+// partition takes its columns from the table it's a partition of.
+func (t tableInfo) createStatement(columns []string) string {
+	var create string
+	if t.partitionOf != "" {
+		create = fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s", t.name, t.partitionOf, t.partBound)
+	} else {
+		create = fmt.Sprintf("CREATE TABLE %s (\n%s\n)", t.name, strings.Join(columns, ",\n"))
+		if len(t.parents) > 0 {
+			create += "\nINHERITS (" + strings.Join(t.parents, ", ") + ")"
+		}
+	}
+	// A partition can be partitioned in turn
+	if t.partitionKey != "" {
+		create += "\nPARTITION BY " + t.partitionKey
+	}
+	return create + ";"
+}
+
+// sequenceStatements creates the sequences of a schema, or only those
+// owned by the table with oid owner if it isn't 0. Identity sequences are
+// left to their columns, and OWNED BY to the table once it exists.
+func sequenceStatements(q catalogQuerier, schema string, owner uint32) ([]string, error) {
+	sql := `SELECT c.oid, quote_ident(n.nspname) || '.' || quote_ident(c.relname), format_type(s.seqtypid, NULL),
+			s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle,
+			pg_get_userbyid(c.relowner)
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('i', 'e'))
+			AND ($2::oid = 0 OR EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.refobjid = $2::oid AND d.deptype = 'a'))
+		ORDER BY c.relname`
+	rows, err := q.Query(context.Background(), sql, schema, owner)
+	if err != nil {
+		log.Printf("Error fetching sequences: %v", err)
+		return nil, err
+	}
+
+	type sequence struct {
+		oid                                  uint32
+		name, typeName, owner                string
+		start, increment, minValue, maxValue int64
+		cache                                int64
+		cycle                                bool
+	}
+	var sequences []sequence
+	for rows.Next() {
+		var s sequence
+		if err := rows.Scan(&s.oid, &s.name, &s.typeName, &s.start, &s.increment, &s.minValue, &s.maxValue,
+			&s.cache, &s.cycle, &s.owner); err != nil {
+			rows.Close()
+			log.Printf("Error scanning sequences: %v", err)
+			return nil, err
+		}
+		sequences = append(sequences, s)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	var statements []string
+	for _, s := range sequences {
+		cycle := "NO CYCLE"
+		if s.cycle {
+			cycle = "CYCLE"
+		}
+		statements = append(statements,
+			fmt.Sprintf("CREATE SEQUENCE %s AS %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d %s;",
+				s.name, s.typeName, s.start, s.increment, s.minValue, s.maxValue, s.cache, cycle),
+			ownerStatement("SEQUENCE", s.name, s.owner))
+		grants, err := grantStatements(q, "SELECT relacl, relowner FROM pg_class WHERE oid = $1", s.oid, "s", "SEQUENCE "+s.name)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, grants...)
+	}
+	return statements, nil
+}
+
+// ownedByStatements ties the sequences of a table's serial columns to it.
+func ownedByStatements(q catalogQuerier, table uint32) ([]string, error) {
+	return queryStrings(q, `SELECT 'ALTER SEQUENCE ' || d.objid::regclass::text || ' OWNED BY ' ||
+			d.refobjid::regclass::text || '.' || quote_ident(a.attname) || ';'
+		FROM pg_depend d
+		JOIN pg_class c ON c.oid = d.objid AND c.relkind = 'S'
+		JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE d.classid = 'pg_class'::regclass AND d.refclassid = 'pg_class'::regclass
+			AND d.refobjid = $1 AND d.deptype = 'a'
+		ORDER BY 1`, table)
+}
+
+// columnAttrs is what a column definition is made of. columnAttrsSQL
+// selects them from pg_attribute a, in the order of columnAttrs.fields.
+type columnAttrs struct {
+	name        string
+	typeName    string
+	collation   string
+	defaultExpr string
+	notNull     bool
+	identity    string
+	generated   string
+}
+
+const columnAttrsSQL = `quote_ident(a.attname), format_type(a.atttypid, a.atttypmod),
+			COALESCE((SELECT ' COLLATE ' || quote_ident(cn.nspname) || '.' || quote_ident(co.collname)
+				FROM pg_collation co JOIN pg_namespace cn ON cn.oid = co.collnamespace
+				WHERE co.oid = a.attcollation AND a.attcollation <> t.typcollation), ''),
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), a.attnotnull, a.attidentity::text, a.attgenerated::text`
+
+const columnAttrsFrom = `FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum`
+
+func (c *columnAttrs) fields() []interface{} {
+	return []interface{}{&c.name, &c.typeName, &c.collation, &c.defaultExpr, &c.notNull, &c.identity, &c.generated}
+}
+
+// definition is the column as written in CREATE TABLE.
+func (c columnAttrs) definition() string {
+	column := c.name + " " + c.typeName + c.collation
+	switch {
+	case c.generated == "s":
+		column += " GENERATED ALWAYS AS (" + c.defaultExpr + ") STORED"
+	case c.identity == "a":
+		column += " GENERATED ALWAYS AS IDENTITY"
+	case c.identity == "d":
+		column += " GENERATED BY DEFAULT AS IDENTITY"
+	case c.defaultExpr != "":
+		column += " DEFAULT " + c.defaultExpr
+	}
+	if c.notNull {
+		column += " NOT NULL"
+	}
+	return column
+}
+
+func columnDefinitions(q catalogQuerier, table uint32) ([]string, []string, error) {
+	sql := `SELECT ` + columnAttrsSQL + `,
+			COALESCE(col_description(a.attrelid, a.attnum), ''), a.attislocal
+		` + columnAttrsFrom + `
+		WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`
+	rows, err := q.Query(context.Background(), sql, table)
+	if err != nil {
+		log.Printf("Error fetching columns: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var columns, comments []string
+	for rows.Next() {
+		var c columnAttrs
+		var comment string
+		var local bool
+		if err := rows.Scan(append(c.fields(), &comment, &local)...); err != nil {
+			log.Printf("Error scanning columns: %v", err)
+			return nil, nil, err
+		}
+		if comment != "" {
+			comments = append(comments, c.name+"\x00"+comment)
+		}
+		// Inherited columns come from the parent
+		if !local {
+			continue
+		}
+		columns = append(columns, "    "+c.definition())
+	}
+	return columns, comments, rows.Err()
+}
+
+func buildTableDDL(q catalogQuerier, t tableInfo) (tableDDL, error) {
+	name := t.name
+	ddl := tableDDL{name: name, parents: t.parents}
+	if t.partitionOf != "" {
+		ddl.parents = []string{t.partitionOf}
+	}
+
+	columns, columnComments, err := columnDefinitions(q, t.oid)
+	if err != nil {
+		return ddl, err
+	}
+
+	ddl.statements = append(ddl.statements, t.createStatement(columns))
+	if t.rowSecurity {
+		ddl.statements = append(ddl.statements, fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", name))
+	}
+
+	constraints, err := queryStrings(q, `SELECT contype::text || 'ALTER TABLE ONLY ' || $2::text || ' ADD CONSTRAINT ' ||
+			quote_ident(conname) || ' ' || pg_get_constraintdef(oid) || ';'
+		FROM pg_constraint
+		WHERE conrelid = $1 AND contype IN ('p', 'u', 'c', 'x', 'f') AND conislocal
+		ORDER BY contype = 'f', conname`, t.oid, name)
+	if err != nil {
+		return ddl, err
+	}
+	for _, c := range constraints {
+		if c[0] == 'f' {
+			ddl.foreignKeys = append(ddl.foreignKeys, c[1:])
+		} else {
+			ddl.statements = append(ddl.statements, c[1:])
+		}
+	}
+
+	indexes, err := queryStrings(q, `SELECT pg_get_indexdef(i.indexrelid) || ';'
+		FROM pg_index i
+		WHERE i.indrelid = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid AND c.conrelid = i.indrelid)
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits h WHERE h.inhrelid = i.indexrelid)
+		ORDER BY 1`, t.oid)
+	if err != nil {
+		return ddl, err
+	}
+	ddl.statements = append(ddl.statements, indexes...)
+
+	ownedBy, err := ownedByStatements(q, t.oid)
+	if err != nil {
+		return ddl, err
+	}
+	ddl.statements = append(ddl.statements, ownedBy...)
+
+	triggers, err := queryStrings(q, `SELECT pg_get_triggerdef(oid) || ';'
+		FROM pg_trigger WHERE tgrelid = $1 AND NOT tgisinternal ORDER BY tgname`, t.oid)
+	if err != nil {
+		return ddl, err
+	}
+	ddl.statements = append(ddl.statements, triggers...)
+
+	if t.comment != "" {
+		ddl.statements = append(ddl.statements, commentStatement("TABLE", name, t.comment))
+	}
+	for _, c := range columnComments {
+		parts := strings.SplitN(c, "\x00", 2)
+		ddl.statements = append(ddl.statements, commentStatement("COLUMN", name+"."+parts[0], parts[1]))
+	}
+
+	ddl.statements = append(ddl.statements, ownerStatement("TABLE", name, t.owner))
+	grants, err := grantStatements(q, "SELECT relacl, relowner FROM pg_class WHERE oid = $1", t.oid, "r", "TABLE "+name)
+	if err != nil {
+		return ddl, err
+	}
+	ddl.statements = append(ddl.statements, grants...)
+
+	columnGrants, err := queryStrings(q, `SELECT 'GRANT ' || string_agg(a.privilege_type, ', ' ORDER BY a.privilege_type) ||
+			' (' || quote_ident(att.attname) || ') ON ' || $2::text || ' TO ' ||
+			CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(pg_get_userbyid(a.grantee)) END ||
+			CASE WHEN a.is_grantable THEN ' WITH GRANT OPTION' ELSE '' END || ';'
+		FROM pg_attribute att, aclexplode(att.attacl) a
+		WHERE att.attrelid = $1 AND att.attnum > 0 AND NOT att.attisdropped
+		GROUP BY att.attnum, att.attname, a.grantee, a.is_grantable
+		ORDER BY att.attnum, 1`, t.oid, name)
+	if err != nil {
+		return ddl, err
+	}
+	ddl.statements = append(ddl.statements, columnGrants...)
+
+	return ddl, nil
+}
+
+// GetTableDDL reconstructs the statements that create a table in the
+// public schema: its sequences, columns, constraints, indexes, triggers,
+// comments, owner and grants.
+func GetTableDDL(conn *pgxpool.Pool, tableName string) (string, error) {
+	var statements []string
+	err := withCatalogSearchPath(conn, func(q catalogQuerier) error {
+		tables, err := getTableInfos(q, "public", tableName)
+		if err != nil {
+			return err
+		}
+		if len(tables) == 0 {
+			return fmt.Errorf("table %s not found", tableName)
+		}
+
+		sequences, err := sequenceStatements(q, "public", tables[0].oid)
+		if err != nil {
+			return err
+		}
+		ddl, err := buildTableDDL(q, tables[0])
+		if err != nil {
+			return err
+		}
+		statements = append(append(sequences, ddl.statements...), ddl.foreignKeys...)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error generating DDL for %s: %v", tableName, err)
+		return "", err
+	}
+	return strings.Join(statements, "\n\n") + "\n", nil
+}
+
+func typeStatements(q catalogQuerier, schema string) ([]string, error) {
+	sql := `SELECT t.oid, format_type(t.oid, NULL), t.typtype::text, pg_get_userbyid(t.typowner),
+			COALESCE(obj_description(t.oid, 'pg_type'), ''),
+			ARRAY(SELECT quote_literal(e.enumlabel) FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder),
+			COALESCE(format_type(NULLIF(t.typbasetype, 0), t.typtypmod), ''), t.typnotnull, COALESCE(t.typdefault, ''),
+			ARRAY(SELECT 'CONSTRAINT ' || quote_ident(c.conname) || ' ' || pg_get_constraintdef(c.oid)
+				FROM pg_constraint c WHERE c.contypid = t.oid ORDER BY c.conname),
+			ARRAY(SELECT quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod)
+				FROM pg_attribute a WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class r ON r.oid = t.typrelid
+		WHERE n.nspname = $1 AND (t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND r.relkind = 'c'))
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e')
+		ORDER BY CASE t.typtype WHEN 'e' THEN 0 WHEN 'c' THEN 1 ELSE 2 END, 2`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching types: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var oid uint32
+		var name, kind, owner, comment, baseType, defaultExpr string
+		var labels, constraints, attributes []string
+		var notNull bool
+		if err := rows.Scan(&oid, &name, &kind, &owner, &comment, &labels, &baseType, &notNull, &defaultExpr,
+			&constraints, &attributes); err != nil {
+			log.Printf("Error scanning types: %v", err)
+			return nil, err
+		}
+
+		objectKind := "TYPE"
+		switch kind {
+		case "e":
+			statements = append(statements, fmt.Sprintf("CREATE TYPE %s AS ENUM (\n    %s\n);", name, strings.Join(labels, ",\n    ")))
+		case "c":
+			statements = append(statements, fmt.Sprintf("CREATE TYPE %s AS (\n    %s\n);", name, strings.Join(attributes, ",\n    ")))
+		case "d":
+			objectKind = "DOMAIN"
+			domain := fmt.Sprintf("CREATE DOMAIN %s AS %s", name, baseType)
+			if defaultExpr != "" {
+				domain += " DEFAULT " + defaultExpr
+			}
+			if notNull {
+				domain += " NOT NULL"
+			}
+			for _, c := range constraints {
+				domain += "\n    " + c
+			}
+			statements = append(statements, domain+";")
+		}
+		if comment != "" {
+			statements = append(statements, commentStatement(objectKind, name, comment))
+		}
+		statements = append(statements, ownerStatement(objectKind, name, owner))
+	}
+	return statements, rows.Err()
+}
+
+// objectDDL holds the statements of a function or view, with the names of
+// the relations and functions it depends on.
+type objectDDL struct {
+	name       string
+	deps       []string
+	statements []string
+}
+
+// functionDDLs creates the functions and procedures of a schema. Their
+// dependencies are the relations whose row types they take or return, or
+// that a SQL-standard body uses, and the functions that body calls.
+func functionDDLs(q catalogQuerier, schema string) ([]objectDDL, error) {
+	sql := `SELECT p.oid, p.oid::regprocedure::text, pg_get_functiondef(p.oid), pg_get_userbyid(p.proowner),
+			COALESCE(obj_description(p.oid, 'pg_proc'), ''),
+			ARRAY(SELECT DISTINCT CASE WHEN d.refclassid = 'pg_proc'::regclass THEN d.refobjid::regprocedure::text
+					ELSE r.oid::regclass::text END
+				FROM pg_depend d
+				LEFT JOIN pg_type t ON d.refclassid = 'pg_type'::regclass AND t.oid = d.refobjid
+				LEFT JOIN pg_type e ON e.oid = t.typelem
+				LEFT JOIN pg_class r ON r.oid = CASE WHEN d.refclassid = 'pg_class'::regclass THEN d.refobjid
+					ELSE COALESCE(NULLIF(t.typrelid, 0), e.typrelid) END
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.refobjid <> p.oid
+					AND (d.refclassid = 'pg_proc'::regclass OR r.relkind IN ('r', 'p', 'v', 'm', 'f')))
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1 AND p.prokind IN ('f', 'p', 'w')
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY 2`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching functions: %v", err)
+		return nil, err
+	}
+
+	type function struct {
+		oid                            uint32
+		signature, def, owner, comment string
+		deps                           []string
+	}
+	var functions []function
+	for rows.Next() {
+		var f function
+		if err := rows.Scan(&f.oid, &f.signature, &f.def, &f.owner, &f.comment, &f.deps); err != nil {
+			rows.Close()
+			log.Printf("Error scanning functions: %v", err)
+			return nil, err
+		}
+		functions = append(functions, f)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	var ddls []objectDDL
+	for _, f := range functions {
+		ddl := objectDDL{name: f.signature, deps: f.deps}
+		ddl.statements = append(ddl.statements, strings.TrimRight(f.def, "\n")+";")
+		if f.comment != "" {
+			ddl.statements = append(ddl.statements, commentStatement("ROUTINE", f.signature, f.comment))
+		}
+		ddl.statements = append(ddl.statements, ownerStatement("ROUTINE", f.signature, f.owner))
+		grants, err := grantStatements(q, "SELECT proacl, proowner FROM pg_proc WHERE oid = $1", f.oid, "f", "ROUTINE "+f.signature)
+		if err != nil {
+			return nil, err
+		}
+		ddl.statements = append(ddl.statements, grants...)
+		ddls = append(ddls, ddl)
+	}
+	return ddls, nil
+}
+
+// viewDDLs creates the views of a schema. Their dependencies are the
+// relations they select from and the functions they call.
+func viewDDLs(q catalogQuerier, schema string) ([]objectDDL, error) {
+	sql := `SELECT c.oid, c.oid::regclass::text, c.relkind = 'm', pg_get_viewdef(c.oid), pg_get_userbyid(c.relowner),
+			COALESCE(obj_description(c.oid, 'pg_class'), ''),
+			ARRAY(SELECT DISTINCT CASE WHEN d.refclassid = 'pg_proc'::regclass THEN d.refobjid::regprocedure::text
+					ELSE d.refobjid::regclass::text END
+				FROM pg_rewrite rw
+				JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = rw.oid
+				WHERE rw.ev_class = c.oid AND d.refclassid IN ('pg_class'::regclass, 'pg_proc'::regclass)
+					AND d.refobjid <> c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('v', 'm')
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		ORDER BY 2`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching views: %v", err)
+		return nil, err
+	}
+
+	type view struct {
+		oid                       uint32
+		name, def, owner, comment string
+		materialized              bool
+		references                []string
+	}
+	var views []view
+	for rows.Next() {
+		var v view
+		if err := rows.Scan(&v.oid, &v.name, &v.materialized, &v.def, &v.owner, &v.comment, &v.references); err != nil {
+			rows.Close()
+			log.Printf("Error scanning views: %v", err)
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	var ddls []objectDDL
+	for _, v := range views {
+		ddl := objectDDL{name: v.name, deps: v.references}
+		def := strings.TrimRight(strings.TrimSpace(v.def), ";")
+		kind := "VIEW"
+		if v.materialized {
+			kind = "MATERIALIZED VIEW"
+			ddl.statements = append(ddl.statements, fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s\nWITH NO DATA;", v.name, def))
+		} else {
+			ddl.statements = append(ddl.statements, fmt.Sprintf("CREATE VIEW %s AS\n%s;", v.name, def))
+		}
+		if v.comment != "" {
+			ddl.statements = append(ddl.statements, commentStatement(kind, v.name, v.comment))
+		}
+		ddl.statements = append(ddl.statements, ownerStatement(kind, v.name, v.owner))
+		grants, err := grantStatements(q, "SELECT relacl, relowner FROM pg_class WHERE oid = $1", v.oid, "r", "TABLE "+v.name)
+		if err != nil {
+			return nil, err
+		}
+		ddl.statements = append(ddl.statements, grants...)
+		ddls = append(ddls, ddl)
+	}
+	return ddls, nil
+}
+
+// orderedStatements lists the statements of objects, each object after
+// those it depends on.
+func orderedStatements(objects []objectDDL) []string {
+	byName := map[string]objectDDL{}
+	var names []string
+	deps := map[string][]string{}
+	for _, o := range objects {
+		byName[o.name] = o
+		names = append(names, o.name)
+		deps[o.name] = o.deps
+	}
+	var statements []string
+	for _, name := range dependencyOrder(names, deps) {
+		statements = append(statements, byName[name].statements...)
+	}
+	return statements
+}
+
+// dependencyOrder sorts names so each comes after the names it depends on.
+// Dependencies outside names are ignored. A cycle, which the server
+// wouldn't allow, is broken by putting its first name last.
+func dependencyOrder(names []string, deps map[string][]string) []string {
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+
+	var ordered []string
+	done := map[string]bool{}
+	visiting := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if done[name] || visiting[name] {
+			return
+		}
+		visiting[name] = true
+		dependencies := append([]string(nil), deps[name]...)
+		sort.Strings(dependencies)
+		for _, dep := range dependencies {
+			if known[dep] {
+				visit(dep)
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+		ordered = append(ordered, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return ordered
+}
+
+// GetSchemaDDL reconstructs a whole schema in dependency order: the schema
+// itself, types, functions, sequences, tables (parents before children),
+// views, and last the foreign keys between tables. Functions that use a
+// table or view of the schema are created among the views, once it exists.
+func GetSchemaDDL(conn *pgxpool.Pool, schema string) (string, error) {
+	sections := []string{ddlHeader}
+	add := func(title string, statements []string) {
+		if len(statements) > 0 {
+			sections = append(sections, "-- "+title+"\n\n"+strings.Join(statements, "\n\n"))
+		}
+	}
+
+	err := withCatalogSearchPath(conn, func(q catalogQuerier) error {
+		var schemaOID uint32
+		var owner, comment string
+		err := q.QueryRow(context.Background(), `SELECT oid, pg_get_userbyid(nspowner), COALESCE(obj_description(oid, 'pg_namespace'), '')
+			FROM pg_namespace WHERE nspname = $1`, schema).Scan(&schemaOID, &owner, &comment)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("schema %s not found", schema)
+		}
+		if err != nil {
+			return err
+		}
+		name := pgx.Identifier{schema}.Sanitize()
+		var schemaStatements []string
+		if schema != "public" {
+			schemaStatements = append(schemaStatements, "CREATE SCHEMA "+name+";")
+		}
+		if comment != "" {
+			schemaStatements = append(schemaStatements, commentStatement("SCHEMA", name, comment))
+		}
+		schemaStatements = append(schemaStatements, ownerStatement("SCHEMA", name, owner))
+		grants, err := grantStatements(q, "SELECT nspacl, nspowner FROM pg_namespace WHERE oid = $1", schemaOID, "n", "SCHEMA "+name)
+		if err != nil {
+			return err
+		}
+		schemaStatements = append(schemaStatements, grants...)
+		add("Schema", schemaStatements)
+
+		types, err := typeStatements(q, schema)
+		if err != nil {
+			return err
+		}
+		add("Types", types)
+
+		functions, err := functionDDLs(q, schema)
+		if err != nil {
+			return err
+		}
+		sequences, err := sequenceStatements(q, schema, 0)
+		if err != nil {
+			return err
+		}
+
+		tables, err := getTableInfos(q, schema, "")
+		if err != nil {
+			return err
+		}
+		byName := map[string]tableDDL{}
+		var names []string
+		deps := map[string][]string{}
+		for _, t := range tables {
+			ddl, err := buildTableDDL(q, t)
+			if err != nil {
+				return err
+			}
+			byName[ddl.name] = ddl
+			names = append(names, ddl.name)
+			deps[ddl.name] = ddl.parents
+		}
+		var tableStatements, foreignKeys []string
+		for _, name := range dependencyOrder(names, deps) {
+			tableStatements = append(tableStatements, byName[name].statements...)
+			foreignKeys = append(foreignKeys, byName[name].foreignKeys...)
+		}
+
+		views, err := viewDDLs(q, schema)
+		if err != nil {
+			return err
+		}
+		relations := map[string]bool{}
+		for _, name := range names {
+			relations[name] = true
+		}
+		for _, v := range views {
+			relations[v.name] = true
+		}
+
+		// A function has to wait for the relations it uses, directly or
+		// through the functions it calls
+		byFunction := map[string]objectDDL{}
+		var functionNames []string
+		functionDeps := map[string][]string{}
+		for _, f := range functions {
+			byFunction[f.name] = f
+			functionNames = append(functionNames, f.name)
+			functionDeps[f.name] = f.deps
+		}
+		late := map[string]bool{}
+		var functionStatements []string
+		var lateObjects []objectDDL
+		for _, name := range dependencyOrder(functionNames, functionDeps) {
+			for _, dep := range functionDeps[name] {
+				if relations[dep] || late[dep] {
+					late[name] = true
+				}
+			}
+			if late[name] {
+				lateObjects = append(lateObjects, byFunction[name])
+			} else {
+				functionStatements = append(functionStatements, byFunction[name].statements...)
+			}
+		}
+
+		add("Functions and procedures", functionStatements)
+		add("Sequences", sequences)
+		add("Tables", tableStatements)
+		add("Views and functions using tables", orderedStatements(append(lateObjects, views...)))
+		add("Foreign keys", foreignKeys)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error generating DDL for schema %s: %v", schema, err)
+		return "", err
+	}
+	return strings.Join(sections, "\n\n") + "\n", nil
+}
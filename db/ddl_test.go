@@ -0,0 +1,56 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		deps  map[string][]string
+		want  []string
+	}{
+		{
+			name:  "no dependencies",
+			names: []string{"b", "a"},
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "parents first",
+			names: []string{"public.child", "public.grandchild", "public.parent"},
+			deps: map[string][]string{
+				"public.child":      {"public.parent"},
+				"public.grandchild": {"public.child"},
+			},
+			want: []string{"public.parent", "public.child", "public.grandchild"},
+		},
+		{
+			name:  "outside dependencies are ignored",
+			names: []string{"public.v"},
+			deps:  map[string][]string{"public.v": {"other.t", "public.f(integer)"}},
+			want:  []string{"public.v"},
+		},
+		{
+			name:  "several dependencies",
+			names: []string{"v3", "v2", "v1"},
+			deps:  map[string][]string{"v3": {"v2", "v1"}},
+			want:  []string{"v1", "v2", "v3"},
+		},
+		{
+			name:  "cycle",
+			names: []string{"a", "b"},
+			deps:  map[string][]string{"a": {"b"}, "b": {"a"}},
+			want:  []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependencyOrder(tt.names, tt.deps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencyOrder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
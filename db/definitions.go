@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// QueryResult is the outcome of the last statement of a query. Columns is
+// empty for statements that return no rows.
+type QueryResult struct {
+	Columns    []string
+	Rows       [][]string
+	CommandTag string
+}
+
+func GetViews(conn *pgxpool.Pool) ([]string, error) {
+	rows, err := conn.Query(context.Background(), "SELECT viewname FROM pg_views WHERE schemaname = 'public' ORDER BY viewname")
+	if err != nil {
+		log.Printf("Error querying views: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []string
+	for rows.Next() {
+		var view string
+		if err := rows.Scan(&view); err != nil {
+			log.Printf("Error while scanning view: %v", err)
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return views, nil
+}
+
+// GetViewDef returns a CREATE OR REPLACE VIEW statement for a view.
+func GetViewDef(conn *pgxpool.Pool, viewName string) (string, error) {
+	name := pgx.Identifier{viewName}.Sanitize()
+	var def string
+	var options []string
+	err := conn.QueryRow(context.Background(), `SELECT pg_get_viewdef(c.oid, true), COALESCE(c.reloptions, '{}')
+		FROM pg_class c WHERE c.oid = $1::regclass`, name).Scan(&def, &options)
+	if err != nil {
+		log.Printf("Error fetching view definition: %v", err)
+		return "", err
+	}
+	return viewDefinition(name, def, options), nil
+}
+
+// viewDefinition builds the statement for a view from its query and
+// reloptions. The check option is stored among the reloptions but is
+// written after the query.
+func viewDefinition(name, def string, options []string) string {
+	var with []string
+	checkOption := ""
+	for _, option := range options {
+		if value, ok := strings.CutPrefix(option, "check_option="); ok {
+			checkOption = fmt.Sprintf("\nWITH %s CHECK OPTION", strings.ToUpper(value))
+			continue
+		}
+		with = append(with, option)
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE OR REPLACE VIEW " + name)
+	if len(with) > 0 {
+		b.WriteString(" WITH (" + strings.Join(with, ", ") + ")")
+	}
+	b.WriteString(" AS\n" + strings.TrimSuffix(strings.TrimRight(def, "\n"), ";"))
+	b.WriteString(checkOption + ";\n")
+	return b.String()
+}
+
+// ApplyDefinition runs an edited definition in a transaction, so a script
+// that fails halfway changes nothing. Errors carry the line they refer to.
+func ApplyDefinition(conn *pgxpool.Pool, sql string) error {
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		log.Printf("Error applying definition: %v", err)
+		return withLine(sql, err)
+	}
+	return tx.Commit(ctx)
+}
+
+// RunQuery runs the statements of a query as one implicit transaction and
+// returns the result of the last one.
+func RunQuery(conn *pgxpool.Pool, sql string) (QueryResult, error) {
+	ctx := context.Background()
+	c, err := conn.Acquire(ctx)
+	if err != nil {
+		log.Printf("Error acquiring connection: %v", err)
+		return QueryResult{}, err
+	}
+	defer c.Release()
+
+	results, err := c.Conn().PgConn().Exec(ctx, sql).ReadAll()
+	if err != nil {
+		log.Printf("Error running query: %v", err)
+		return QueryResult{}, withLine(sql, err)
+	}
+	if len(results) == 0 {
+		return QueryResult{}, nil
+	}
+
+	last := results[len(results)-1]
+	result := QueryResult{CommandTag: last.CommandTag.String()}
+	for _, f := range last.FieldDescriptions {
+		result.Columns = append(result.Columns, string(f.Name))
+	}
+	for _, row := range last.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				values[i] = "NULL"
+			} else {
+				values[i] = string(v)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, nil
+}
+
+var (
+	plpgsqlLinePattern = regexp.MustCompile(`(?:near|at) line (\d+)`)
+	dollarQuotePattern = regexp.MustCompile(`\$[A-Za-z_]*\$`)
+)
+
+// withLine prefixes a server error with the line of sql it points at. The
+// server gives a character position for most errors, PL/pgSQL reports a
+// line of the function body instead, which starts at the dollar quote.
+func withLine(sql string, err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if pgErr.Position > 0 {
+		runes := []rune(sql)
+		pos := min(int(pgErr.Position)-1, len(runes))
+		return fmt.Errorf("line %d: %w", strings.Count(string(runes[:pos]), "\n")+1, err)
+	}
+
+	match := plpgsqlLinePattern.FindStringSubmatch(pgErr.Where)
+	body := dollarQuotePattern.FindStringIndex(sql)
+	if match == nil || body == nil {
+		return err
+	}
+	bodyLine, _ := strconv.Atoi(match[1])
+	return fmt.Errorf("line %d: %w", strings.Count(sql[:body[0]], "\n")+bodyLine, err)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestWithLine(t *testing.T) {
+	sql := "CREATE FUNCTION f() RETURNS int\nLANGUAGE plpgsql AS $$\nBEGIN\n  RETURN x;\nEND\n$$;"
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "not a server error",
+			err:  errors.New("conn closed"),
+			want: "conn closed",
+		},
+		{
+			name: "position",
+			err:  &pgconn.PgError{Severity: "ERROR", Code: "42601", Message: "syntax error", Position: 34},
+			want: "line 2: ERROR: syntax error (SQLSTATE 42601)",
+		},
+		{
+			name: "plpgsql line",
+			err:  &pgconn.PgError{Severity: "ERROR", Code: "42703", Message: "column \"x\" does not exist", Where: "compilation of PL/pgSQL function \"f\" near line 3"},
+			want: "line 4: ERROR: column \"x\" does not exist (SQLSTATE 42703)",
+		},
+		{
+			name: "no location",
+			err:  &pgconn.PgError{Severity: "ERROR", Code: "42501", Message: "permission denied"},
+			want: "ERROR: permission denied (SQLSTATE 42501)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withLine(sql, tt.err)
+			if got.Error() != tt.want {
+				t.Errorf("withLine() = %q, want %q", got.Error(), tt.want)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("withLine() doesn't wrap %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestViewDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    string
+	}{
+		{
+			name: "no options",
+			want: "CREATE OR REPLACE VIEW v AS\n SELECT 1;\n",
+		},
+		{
+			name:    "options and check option",
+			options: []string{"security_barrier=true", "check_option=cascaded", "security_invoker=true"},
+			want:    "CREATE OR REPLACE VIEW v WITH (security_barrier=true, security_invoker=true) AS\n SELECT 1\nWITH CASCADED CHECK OPTION;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := viewDefinition("v", " SELECT 1;", tt.options); got != tt.want {
+				t.Errorf("viewDefinition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
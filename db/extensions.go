@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Extension is an extension available on the server. InstalledVersion and
+// Schema are empty unless it is installed in the current database.
+type Extension struct {
+	Name             string
+	DefaultVersion   string
+	InstalledVersion string
+	Schema           string
+	Comment          string
+	Versions         []string
+}
+
+// GetExtensions lists the installed extensions first, then the ones that
+// could be installed. Versions made of dotted numbers are ordered
+// numerically, so 1.10 comes after 1.9; any others follow as text.
+func GetExtensions(conn *pgxpool.Pool) ([]Extension, error) {
+	sql := `SELECT a.name, COALESCE(a.default_version, ''), COALESCE(a.installed_version, ''),
+			COALESCE(n.nspname, ''), COALESCE(a.comment, ''),
+			ARRAY(SELECT v.version FROM pg_available_extension_versions v WHERE v.name = a.name
+				ORDER BY CASE WHEN v.version ~ '^[0-9]+(\.[0-9]+)*$' THEN string_to_array(v.version, '.')::numeric[] END, v.version)
+		FROM pg_available_extensions a
+		LEFT JOIN pg_extension e ON e.extname = a.name
+		LEFT JOIN pg_namespace n ON n.oid = e.extnamespace
+		ORDER BY a.installed_version IS NULL, a.name`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching extensions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var extensions []Extension
+	for rows.Next() {
+		var e Extension
+		if err := rows.Scan(&e.Name, &e.DefaultVersion, &e.InstalledVersion, &e.Schema, &e.Comment, &e.Versions); err != nil {
+			log.Printf("Error scanning extensions: %v", err)
+			return nil, err
+		}
+		extensions = append(extensions, e)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return extensions, nil
+}
+
+// GetExtensionDependents describes the objects outside an extension that
+// depend on it, such as columns of its types or extensions requiring it.
+// DROP EXTENSION fails while there are any, unless CASCADE drops them too.
+func GetExtensionDependents(conn *pgxpool.Pool, name string) ([]string, error) {
+	sql := `WITH ext AS (
+			SELECT oid FROM pg_extension WHERE extname = $1
+		), members AS (
+			SELECT d.classid, d.objid FROM pg_depend d, ext
+			WHERE d.refclassid = 'pg_extension'::regclass AND d.refobjid = ext.oid AND d.deptype = 'e'
+		)
+		SELECT pg_describe_object(d.classid, d.objid, d.objsubid)
+		FROM pg_depend d
+		JOIN members m ON d.refclassid = m.classid AND d.refobjid = m.objid
+		WHERE d.deptype IN ('n', 'a')
+			AND NOT EXISTS (SELECT 1 FROM members o WHERE o.classid = d.classid AND o.objid = d.objid)
+		UNION
+		SELECT pg_describe_object(d.classid, d.objid, d.objsubid)
+		FROM pg_depend d, ext
+		WHERE d.refclassid = 'pg_extension'::regclass AND d.refobjid = ext.oid AND d.deptype = 'n'
+		ORDER BY 1`
+	rows, err := conn.Query(context.Background(), sql, name)
+	if err != nil {
+		log.Printf("Error querying extension dependents: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return objects, nil
+}
+
+// CreateExtensionDDL installs an extension. An empty schema or version
+// means the extension's default, cascade also installs the extensions it
+// requires.
+func CreateExtensionDDL(name, schema, version string, cascade bool) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("extension name is required")
+	}
+	var options []string
+	if schema != "" {
+		options = append(options, "SCHEMA "+pgx.Identifier{schema}.Sanitize())
+	}
+	if version != "" {
+		options = append(options, "VERSION "+quoteLiteral(version))
+	}
+	if cascade {
+		options = append(options, "CASCADE")
+	}
+
+	ddl := "CREATE EXTENSION " + pgx.Identifier{name}.Sanitize()
+	if len(options) > 0 {
+		ddl += " WITH " + strings.Join(options, " ")
+	}
+	return ddl, nil
+}
+
+// UpdateExtensionDDL updates an extension to version, or to its default
+// version if version is empty.
+func UpdateExtensionDDL(name, version string) string {
+	ddl := "ALTER EXTENSION " + pgx.Identifier{name}.Sanitize() + " UPDATE"
+	if version != "" {
+		ddl += " TO " + quoteLiteral(version)
+	}
+	return ddl
+}
+
+func DropExtensionDDL(name string, cascade bool) string {
+	ddl := "DROP EXTENSION " + pgx.Identifier{name}.Sanitize()
+	if cascade {
+		ddl += " CASCADE"
+	}
+	return ddl
+}
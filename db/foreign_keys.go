@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ForeignKey struct {
+	Name       string
+	Table      string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+const foreignKeysQuery = `SELECT c.conname, cl.relname, rf.relname,
+		ARRAY(SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum ORDER BY k.ord)::text[],
+		ARRAY(SELECT a.attname FROM unnest(c.confkey) WITH ORDINALITY k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum ORDER BY k.ord)::text[]
+	FROM pg_constraint c
+	JOIN pg_class cl ON cl.oid = c.conrelid
+	JOIN pg_class rf ON rf.oid = c.confrelid
+	WHERE c.contype = 'f'`
+
+// GetForeignKeys returns the foreign keys defined on a table.
+func GetForeignKeys(conn *pgxpool.Pool, tableName string) ([]ForeignKey, error) {
+	return queryForeignKeys(conn, foreignKeysQuery+" AND c.conrelid = $1::regclass ORDER BY c.conname", tableName)
+}
+
+// GetReferencingForeignKeys returns the foreign keys in other tables (or the
+// same one) that point at a table.
+func GetReferencingForeignKeys(conn *pgxpool.Pool, tableName string) ([]ForeignKey, error) {
+	return queryForeignKeys(conn, foreignKeysQuery+" AND c.confrelid = $1::regclass ORDER BY cl.relname, c.conname", tableName)
+}
+
+func queryForeignKeys(conn *pgxpool.Pool, sql, tableName string) ([]ForeignKey, error) {
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error querying foreign keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		if err := rows.Scan(&fk.Name, &fk.Table, &fk.RefTable, &fk.Columns, &fk.RefColumns); err != nil {
+			log.Printf("Error while scanning foreign key: %v", err)
+			return nil, err
+		}
+		keys = append(keys, fk)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return keys, nil
+}
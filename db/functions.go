@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Function is a user-defined function or procedure.
+type Function struct {
+	OID       uint32
+	Schema    string
+	Name      string
+	Arguments string
+	// Result is empty for procedures
+	Result          string
+	Kind            string
+	Language        string
+	Volatility      string
+	SecurityDefiner bool
+}
+
+// Trigger is a trigger on a table. Enabled is one of enabled, disabled,
+// replica or always, following session_replication_role.
+type Trigger struct {
+	Name        string
+	Timing      string
+	Events      string
+	Level       string
+	Function    string
+	FunctionOID uint32
+	Enabled     string
+}
+
+// GetFunctions lists the functions and procedures outside the system
+// schemas, leaving out those that belong to extensions.
+func GetFunctions(conn *pgxpool.Pool) ([]Function, error) {
+	sql := `SELECT p.oid, n.nspname, p.proname, pg_get_function_identity_arguments(p.oid),
+			COALESCE(pg_get_function_result(p.oid), ''),
+			CASE p.prokind WHEN 'p' THEN 'procedure' WHEN 'a' THEN 'aggregate' WHEN 'w' THEN 'window' ELSE 'function' END,
+			l.lanname,
+			CASE p.provolatile WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END,
+			p.prosecdef
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		JOIN pg_language l ON l.oid = p.prolang
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY n.nspname, p.proname, 4`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching functions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var functions []Function
+	for rows.Next() {
+		var f Function
+		if err := rows.Scan(&f.OID, &f.Schema, &f.Name, &f.Arguments, &f.Result, &f.Kind,
+			&f.Language, &f.Volatility, &f.SecurityDefiner); err != nil {
+			log.Printf("Error scanning functions: %v", err)
+			return nil, err
+		}
+		functions = append(functions, f)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return functions, nil
+}
+
+// GetFunctionDef returns the CREATE OR REPLACE statement of a function or
+// procedure. Aggregates have no such definition.
+func GetFunctionDef(conn *pgxpool.Pool, oid uint32) (string, error) {
+	var def string
+	err := conn.QueryRow(context.Background(), "SELECT pg_get_functiondef($1)", oid).Scan(&def)
+	if err != nil {
+		log.Printf("Error fetching function definition: %v", err)
+		return "", err
+	}
+	return def, nil
+}
+
+// GetTriggers lists the triggers on a table, without the internal ones
+// that implement foreign keys.
+func GetTriggers(conn *pgxpool.Pool, tableName string) ([]Trigger, error) {
+	sql := `SELECT t.tgname,
+			CASE WHEN t.tgtype & 2 <> 0 THEN 'BEFORE' WHEN t.tgtype & 64 <> 0 THEN 'INSTEAD OF' ELSE 'AFTER' END,
+			array_to_string(ARRAY[
+				CASE WHEN t.tgtype & 4 <> 0 THEN 'INSERT' END,
+				CASE WHEN t.tgtype & 16 <> 0 THEN 'UPDATE' END,
+				CASE WHEN t.tgtype & 8 <> 0 THEN 'DELETE' END,
+				CASE WHEN t.tgtype & 32 <> 0 THEN 'TRUNCATE' END], ' OR '),
+			CASE WHEN t.tgtype & 1 <> 0 THEN 'ROW' ELSE 'STATEMENT' END,
+			t.tgfoid::regproc::text, t.tgfoid,
+			CASE t.tgenabled WHEN 'O' THEN 'enabled' WHEN 'D' THEN 'disabled' WHEN 'R' THEN 'replica' ELSE 'always' END
+		FROM pg_trigger t
+		WHERE t.tgrelid = $1::regclass AND NOT t.tgisinternal
+		ORDER BY t.tgname`
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error fetching triggers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []Trigger
+	for rows.Next() {
+		var t Trigger
+		if err := rows.Scan(&t.Name, &t.Timing, &t.Events, &t.Level, &t.Function, &t.FunctionOID, &t.Enabled); err != nil {
+			log.Printf("Error scanning triggers: %v", err)
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return triggers, nil
+}
+
+// SetTriggerEnabledDDL enables or disables a trigger.
+func SetTriggerEnabledDDL(tableName, triggerName string, enabled bool) string {
+	action := "DISABLE"
+	if enabled {
+		action = "ENABLE"
+	}
+	return alterTable(tableName) + " " + action + " TRIGGER " + pgx.Identifier{triggerName}.Sanitize()
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Index struct {
+	Name       string
+	Definition string
+	Size       string
+	Scans      int64
+	TuplesRead int64
+	Unique     bool
+	Primary    bool
+}
+
+// Unused reports whether the planner has never used the index since the
+// statistics were last reset. Unique indexes are never considered unused
+// because they enforce a constraint.
+func (i Index) Unused() bool {
+	return i.Scans == 0 && !i.Unique && !i.Primary
+}
+
+func GetIndexes(conn *pgxpool.Pool, tableName string) ([]Index, error) {
+	sql := `SELECT c.relname, pg_get_indexdef(i.indexrelid), pg_size_pretty(pg_relation_size(i.indexrelid)),
+			COALESCE(s.idx_scan, 0), COALESCE(s.idx_tup_read, 0), i.indisunique, i.indisprimary
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = i.indexrelid
+		WHERE i.indrelid = $1::regclass
+		ORDER BY c.relname`
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error querying indexes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var idx Index
+		if err := rows.Scan(&idx.Name, &idx.Definition, &idx.Size, &idx.Scans, &idx.TuplesRead, &idx.Unique, &idx.Primary); err != nil {
+			log.Printf("Error while scanning index: %v", err)
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return indexes, nil
+}
+
+var IndexMethods = []string{"btree", "hash", "gin", "gist", "spgist", "brin"}
+
+type IndexDef struct {
+	Name         string
+	Columns      []string
+	Method       string
+	Unique       bool
+	Where        string
+	Concurrently bool
+}
+
+// CreateIndexDDL builds a CREATE INDEX statement. Where is a SQL predicate
+// for a partial index and is used verbatim once validateExpression accepts
+// it.
+func CreateIndexDDL(tableName string, def IndexDef) (string, error) {
+	if len(def.Columns) == 0 {
+		return "", fmt.Errorf("an index needs at least one column")
+	}
+
+	method := def.Method
+	if method == "" {
+		method = "btree"
+	}
+	valid := false
+	for _, m := range IndexMethods {
+		valid = valid || m == method
+	}
+	if !valid {
+		return "", fmt.Errorf("unknown index method %q, expected one of %s", method, strings.Join(IndexMethods, ", "))
+	}
+	if def.Unique && method != "btree" {
+		return "", fmt.Errorf("only btree indexes can be unique")
+	}
+
+	columns := make([]string, len(def.Columns))
+	for i, col := range def.Columns {
+		columns[i] = pgx.Identifier{col}.Sanitize()
+	}
+
+	ddl := "CREATE "
+	if def.Unique {
+		ddl += "UNIQUE "
+	}
+	ddl += "INDEX "
+	if def.Concurrently {
+		ddl += "CONCURRENTLY "
+	}
+	if def.Name != "" {
+		ddl += pgx.Identifier{def.Name}.Sanitize() + " "
+	}
+	ddl += fmt.Sprintf("ON %s USING %s (%s)", pgx.Identifier{tableName}.Sanitize(), method, strings.Join(columns, ", "))
+	if def.Where != "" {
+		if err := validateExpression(def.Where); err != nil {
+			return "", fmt.Errorf("where: %w", err)
+		}
+		ddl += " WHERE " + def.Where
+	}
+	return ddl, nil
+}
+
+func DropIndexDDL(indexName string, concurrently bool) string {
+	if concurrently {
+		return "DROP INDEX CONCURRENTLY " + pgx.Identifier{indexName}.Sanitize()
+	}
+	return "DROP INDEX " + pgx.Identifier{indexName}.Sanitize()
+}
@@ -0,0 +1,16 @@
+package db
+
+import "testing"
+
+func TestCreateIndexDDL(t *testing.T) {
+	def := IndexDef{Name: "active_email", Columns: []string{"email"}, Unique: true, Where: "deleted_at IS NULL"}
+	got, err := CreateIndexDDL("users", def)
+	if want := `CREATE UNIQUE INDEX "active_email" ON "users" USING btree ("email") WHERE deleted_at IS NULL`; err != nil || got != want {
+		t.Errorf("CreateIndexDDL() = %q, %v, want %q", got, err, want)
+	}
+
+	def.Where = "true; DROP TABLE users"
+	if _, err := CreateIndexDDL("users", def); err == nil {
+		t.Errorf("CreateIndexDDL() accepted WHERE %q", def.Where)
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Lock is a lock a backend holds or waits for.
+type Lock struct {
+	Mode string
+	// Object is the relation name, or the lock type for locks on other
+	// objects such as transaction IDs
+	Object  string
+	Granted bool
+}
+
+// LockedBackend is a backend that is blocked or blocks another one.
+type LockedBackend struct {
+	PID         int
+	User        string
+	Application string
+	State       string
+	// Duration is how long its transaction has been open
+	Duration  time.Duration
+	Query     string
+	BlockedBy []int
+	Locks     []Lock
+}
+
+// GetBlockingChains returns every backend that waits for a lock together
+// with the backends holding it. Only relation locks and locks being waited
+// for are listed, the rest are mostly noise.
+func GetBlockingChains(conn *pgxpool.Pool) ([]LockedBackend, error) {
+	sql := `WITH blocked AS (
+			SELECT pid, pg_blocking_pids(pid) AS blockers
+			FROM pg_stat_activity
+			WHERE cardinality(pg_blocking_pids(pid)) > 0
+		), involved AS (
+			SELECT pid FROM blocked
+			UNION
+			SELECT unnest(blockers) FROM blocked
+		), locks AS (
+			-- One read of pg_locks, numbered so the arrays below line up.
+			-- Relation OIDs only mean something in the current database.
+			SELECT l.pid, l.mode, COALESCE(l.relation::regclass::text, l.locktype) AS object, l.granted,
+				row_number() OVER (PARTITION BY l.pid ORDER BY l.granted, l.relation, l.mode, l.locktype) AS n
+			FROM pg_locks l
+			WHERE (NOT l.granted OR l.relation IS NOT NULL)
+				AND (l.relation IS NULL OR l.database IN (0, (SELECT oid FROM pg_database WHERE datname = current_database())))
+		)
+		SELECT a.pid, COALESCE(a.usename, ''), a.application_name, COALESCE(a.state, ''),
+			COALESCE(extract(epoch FROM now() - a.xact_start), 0)::float8, a.query,
+			COALESCE(b.blockers, '{}'),
+			COALESCE(lk.modes, '{}'), COALESCE(lk.objects, '{}'), COALESCE(lk.granted, '{}')
+		FROM involved i
+		JOIN pg_stat_activity a ON a.pid = i.pid
+		LEFT JOIN blocked b ON b.pid = a.pid
+		LEFT JOIN LATERAL (
+			SELECT array_agg(l.mode ORDER BY l.n) AS modes, array_agg(l.object ORDER BY l.n) AS objects,
+				array_agg(l.granted ORDER BY l.n) AS granted
+			FROM locks l WHERE l.pid = a.pid
+		) lk ON true
+		ORDER BY a.xact_start NULLS LAST, a.pid`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching locks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backends []LockedBackend
+	for rows.Next() {
+		var b LockedBackend
+		var seconds float64
+		var blockedBy []int32
+		var modes, objects []string
+		var granted []bool
+		if err := rows.Scan(&b.PID, &b.User, &b.Application, &b.State, &seconds, &b.Query,
+			&blockedBy, &modes, &objects, &granted); err != nil {
+			log.Printf("Error scanning locks: %v", err)
+			return nil, err
+		}
+		b.Duration = time.Duration(seconds * float64(time.Second))
+		for _, pid := range blockedBy {
+			b.BlockedBy = append(b.BlockedBy, int(pid))
+		}
+		for i := range modes[:min(len(modes), len(objects), len(granted))] {
+			b.Locks = append(b.Locks, Lock{Mode: modes[i], Object: objects[i], Granted: granted[i]})
+		}
+		backends = append(backends, b)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return backends, nil
+}
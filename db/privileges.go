@@ -0,0 +1,365 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PrivilegeObject is something privileges are granted on. Tables and
+// sequences are the ones in the public schema.
+type PrivilegeObject struct {
+	Kind string // "database", "schema", "table" or "sequence"
+	Name string
+}
+
+// ObjectPrivileges lists the privileges that apply to each kind of object.
+var ObjectPrivileges = map[string][]string{
+	"database": {"CONNECT", "CREATE", "TEMPORARY"},
+	"schema":   {"USAGE", "CREATE"},
+	"table":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+	"sequence": {"USAGE", "SELECT", "UPDATE"},
+}
+
+// aclPrivileges maps the letters of an aclitem to privilege names.
+var aclPrivileges = map[byte]string{
+	'r': "SELECT",
+	'a': "INSERT",
+	'w': "UPDATE",
+	'd': "DELETE",
+	'D': "TRUNCATE",
+	'x': "REFERENCES",
+	't': "TRIGGER",
+	'X': "EXECUTE",
+	'U': "USAGE",
+	'C': "CREATE",
+	'c': "CONNECT",
+	'T': "TEMPORARY",
+	'm': "MAINTAIN",
+}
+
+// PublicRole is the grantee name used for privileges granted to everyone.
+const PublicRole = "PUBLIC"
+
+// RolePrivileges is one row of the privilege matrix.
+type RolePrivileges struct {
+	Role string
+	// Granted and GrantOption come straight from the object's ACL
+	Granted     map[string]bool
+	GrantOption map[string]bool
+	// Effective also counts membership in other roles, ownership and
+	// superuser
+	Effective map[string]bool
+}
+
+func GetPrivilegeObjects(conn *pgxpool.Pool) ([]PrivilegeObject, error) {
+	sql := `SELECT 'database', datname FROM pg_database WHERE NOT datistemplate
+		UNION ALL
+		SELECT 'schema', nspname FROM pg_namespace WHERE nspname !~ '^pg_' AND nspname <> 'information_schema'
+		UNION ALL
+		SELECT CASE WHEN c.relkind = 'S' THEN 'sequence' ELSE 'table' END, c.relname
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		ORDER BY 1, 2`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching privilege objects: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []PrivilegeObject
+	for rows.Next() {
+		var o PrivilegeObject
+		if err := rows.Scan(&o.Kind, &o.Name); err != nil {
+			log.Printf("Error scanning privilege object: %v", err)
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return objects, nil
+}
+
+// GetPrivileges builds the role × privilege matrix of an object. A NULL ACL
+// means the object still has its built-in default privileges, which
+// acldefault spells out.
+func GetPrivileges(conn *pgxpool.Pool, obj PrivilegeObject) ([]RolePrivileges, error) {
+	var aclSQL, hasPrivilege, name string
+	switch obj.Kind {
+	case "database":
+		aclSQL = `SELECT COALESCE(datacl, acldefault('d', datdba))::text[] FROM pg_database WHERE datname = $1`
+		hasPrivilege, name = "has_database_privilege", obj.Name
+	case "schema":
+		aclSQL = `SELECT COALESCE(nspacl, acldefault('n', nspowner))::text[] FROM pg_namespace WHERE nspname = $1`
+		hasPrivilege, name = "has_schema_privilege", obj.Name
+	case "table":
+		aclSQL = `SELECT COALESCE(relacl, acldefault('r', relowner))::text[] FROM pg_class WHERE oid = $1::regclass`
+		hasPrivilege, name = "has_table_privilege", pgx.Identifier{obj.Name}.Sanitize()
+	case "sequence":
+		aclSQL = `SELECT COALESCE(relacl, acldefault('s', relowner))::text[] FROM pg_class WHERE oid = $1::regclass`
+		hasPrivilege, name = "has_sequence_privilege", pgx.Identifier{obj.Name}.Sanitize()
+	default:
+		return nil, fmt.Errorf("unknown object kind %q", obj.Kind)
+	}
+
+	var acl []string
+	if err := conn.QueryRow(context.Background(), aclSQL, name).Scan(&acl); err != nil {
+		log.Printf("Error fetching ACL: %v", err)
+		return nil, err
+	}
+
+	privileges := ObjectPrivileges[obj.Kind]
+	public := RolePrivileges{Role: PublicRole, Granted: map[string]bool{}, GrantOption: map[string]bool{}}
+	matrix := []RolePrivileges{public}
+	byRole := map[string]RolePrivileges{"": public}
+
+	checks := make([]string, len(privileges))
+	for i, p := range privileges {
+		checks[i] = fmt.Sprintf("%s(r.oid, $1, '%s')", hasPrivilege, p)
+	}
+	sql := fmt.Sprintf(`SELECT r.rolname, ARRAY[%s] FROM pg_roles r WHERE r.rolname !~ '^pg_' ORDER BY r.rolname`, strings.Join(checks, ", "))
+	rows, err := conn.Query(context.Background(), sql, name)
+	if err != nil {
+		log.Printf("Error checking privileges: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		var has []bool
+		if err := rows.Scan(&role, &has); err != nil {
+			log.Printf("Error scanning privileges: %v", err)
+			return nil, err
+		}
+		rp := RolePrivileges{Role: role, Granted: map[string]bool{}, GrantOption: map[string]bool{}, Effective: map[string]bool{}}
+		for i, p := range privileges {
+			rp.Effective[p] = has[i]
+		}
+		matrix = append(matrix, rp)
+		byRole[role] = rp
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	for _, item := range acl {
+		grantee, granted, grantOption, err := parseACLItem(item)
+		if err != nil {
+			return nil, err
+		}
+		rp, ok := byRole[grantee]
+		if !ok {
+			continue
+		}
+		for _, p := range granted {
+			rp.Granted[p] = true
+		}
+		for _, p := range grantOption {
+			rp.GrantOption[p] = true
+		}
+	}
+
+	// PUBLIC has no memberships, what it is granted is all it has
+	matrix[0].Effective = matrix[0].Granted
+
+	return matrix, nil
+}
+
+// parseACLItem decodes an aclitem such as "alice=arw*/postgres" into the
+// grantee, empty for PUBLIC, its privileges and the ones it may grant on.
+func parseACLItem(item string) (string, []string, []string, error) {
+	grantee, rest, err := parseACLRole(item)
+	if err != nil || !strings.HasPrefix(rest, "=") {
+		return "", nil, nil, fmt.Errorf("invalid aclitem %q", item)
+	}
+	rest = rest[1:]
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest = rest[:i]
+	}
+
+	var granted, grantOption []string
+	for i := 0; i < len(rest); i++ {
+		p, ok := aclPrivileges[rest[i]]
+		if !ok {
+			return "", nil, nil, fmt.Errorf("invalid privilege %q in aclitem %q", rest[i], item)
+		}
+		granted = append(granted, p)
+		if i+1 < len(rest) && rest[i+1] == '*' {
+			grantOption = append(grantOption, p)
+			i++
+		}
+	}
+	return grantee, granted, grantOption, nil
+}
+
+// parseACLRole reads a role name at the start of an aclitem, which is
+// double quoted when it contains special characters.
+func parseACLRole(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, "=/")
+		if i < 0 {
+			return s, "", nil
+		}
+		return s[:i], s[i:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+			continue
+		}
+		return b.String(), s[i+1:], nil
+	}
+	return "", "", fmt.Errorf("unterminated role name in %q", s)
+}
+
+func granteeSQL(grantee string) string {
+	if grantee == PublicRole {
+		return PublicRole
+	}
+	return pgx.Identifier{grantee}.Sanitize()
+}
+
+func (o PrivilegeObject) sql() string {
+	return strings.ToUpper(o.Kind) + " " + pgx.Identifier{o.Name}.Sanitize()
+}
+
+func GrantDDL(obj PrivilegeObject, privilege, grantee string) string {
+	return fmt.Sprintf("GRANT %s ON %s TO %s", privilege, obj.sql(), granteeSQL(grantee))
+}
+
+func RevokeDDL(obj PrivilegeObject, privilege, grantee string) string {
+	return fmt.Sprintf("REVOKE %s ON %s FROM %s", privilege, obj.sql(), granteeSQL(grantee))
+}
+
+// DefaultPrivilege is what a grantee receives on objects an owner creates
+// later, as set with ALTER DEFAULT PRIVILEGES.
+type DefaultPrivilege struct {
+	Owner string
+	// Schema is empty for defaults that apply in every schema
+	Schema     string
+	ObjectType string
+	Grantee    string
+	Privileges []string
+}
+
+// DefaultPrivilegeTypes maps the object types of ALTER DEFAULT PRIVILEGES to
+// the privileges that apply to them.
+var DefaultPrivilegeTypes = map[string][]string{
+	"TABLES":    ObjectPrivileges["table"],
+	"SEQUENCES": ObjectPrivileges["sequence"],
+	"FUNCTIONS": {"EXECUTE"},
+	"TYPES":     {"USAGE"},
+	"SCHEMAS":   ObjectPrivileges["schema"],
+}
+
+// DefaultPrivilegeTypeNames lists the keys of DefaultPrivilegeTypes in a
+// stable order.
+var DefaultPrivilegeTypeNames = []string{"TABLES", "SEQUENCES", "FUNCTIONS", "TYPES", "SCHEMAS"}
+
+func GetDefaultPrivileges(conn *pgxpool.Pool) ([]DefaultPrivilege, error) {
+	sql := `SELECT pg_get_userbyid(d.defaclrole), COALESCE(n.nspname, ''),
+			CASE d.defaclobjtype WHEN 'r' THEN 'TABLES' WHEN 'S' THEN 'SEQUENCES' WHEN 'f' THEN 'FUNCTIONS'
+				WHEN 'T' THEN 'TYPES' WHEN 'n' THEN 'SCHEMAS' END,
+			d.defaclacl::text[]
+		FROM pg_default_acl d LEFT JOIN pg_namespace n ON n.oid = d.defaclnamespace
+		ORDER BY 1, 2, 3`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching default privileges: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defaults []DefaultPrivilege
+	for rows.Next() {
+		var owner, schema, objectType string
+		var acl []string
+		if err := rows.Scan(&owner, &schema, &objectType, &acl); err != nil {
+			log.Printf("Error scanning default privileges: %v", err)
+			return nil, err
+		}
+		for _, item := range acl {
+			grantee, granted, _, err := parseACLItem(item)
+			if err != nil {
+				return nil, err
+			}
+			if grantee == "" {
+				grantee = PublicRole
+			}
+			defaults = append(defaults, DefaultPrivilege{
+				Owner:      owner,
+				Schema:     schema,
+				ObjectType: objectType,
+				Grantee:    grantee,
+				Privileges: granted,
+			})
+		}
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return defaults, nil
+}
+
+// AlterDefaultPrivilegesDDL grants or revokes default privileges. An empty
+// schema applies them in every schema.
+func AlterDefaultPrivilegesDDL(owner, schema string, grant bool, privileges []string, objectType, grantee string) (string, error) {
+	allowed, ok := DefaultPrivilegeTypes[objectType]
+	if !ok {
+		return "", fmt.Errorf("unknown object type %q", objectType)
+	}
+	if len(privileges) == 0 {
+		return "", fmt.Errorf("at least one privilege is required")
+	}
+	for i, p := range privileges {
+		p = strings.ToUpper(p)
+		if p != "ALL" && indexOf(allowed, p) < 0 {
+			return "", fmt.Errorf("%s does not apply to %s, use one of %s or ALL", p, strings.ToLower(objectType), strings.Join(allowed, ", "))
+		}
+		privileges[i] = p
+	}
+	if owner == "" || grantee == "" {
+		return "", fmt.Errorf("owner and grantee cannot be empty")
+	}
+
+	ddl := "ALTER DEFAULT PRIVILEGES FOR ROLE " + pgx.Identifier{owner}.Sanitize()
+	if schema != "" {
+		if objectType == "SCHEMAS" {
+			return "", fmt.Errorf("default privileges on schemas cannot be limited to a schema")
+		}
+		ddl += " IN SCHEMA " + pgx.Identifier{schema}.Sanitize()
+	}
+	if grant {
+		return fmt.Sprintf("%s GRANT %s ON %s TO %s", ddl, strings.Join(privileges, ", "), objectType, granteeSQL(grantee)), nil
+	}
+	return fmt.Sprintf("%s REVOKE %s ON %s FROM %s", ddl, strings.Join(privileges, ", "), objectType, granteeSQL(grantee)), nil
+}
+
+func indexOf(items []string, item string) int {
+	for i, it := range items {
+		if it == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+type RoleAttributes struct {
+	Superuser       bool
+	CreateDB        bool
+	CreateRole      bool
+	Login           bool
+	Replication     bool
+	ConnectionLimit int
+	// ValidUntil is a timestamp as text, empty when the password never expires
+	ValidUntil string
+}
+
+type Role struct {
+	Name string
+	RoleAttributes
+	MemberOf []string
+}
+
+func GetRoles(conn *pgxpool.Pool) ([]Role, error) {
+	sql := `SELECT r.rolname, r.rolsuper, r.rolcreatedb, r.rolcreaterole, r.rolcanlogin, r.rolreplication,
+			r.rolconnlimit, COALESCE(r.rolvaliduntil::text, ''),
+			ARRAY(SELECT b.rolname FROM pg_auth_members am JOIN pg_roles b ON b.oid = am.roleid
+				WHERE am.member = r.oid ORDER BY b.rolname)::text[]
+		FROM pg_roles r
+		WHERE r.rolname !~ '^pg_'
+		ORDER BY r.rolname`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching roles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var r Role
+		if err := rows.Scan(&r.Name, &r.Superuser, &r.CreateDB, &r.CreateRole, &r.Login, &r.Replication,
+			&r.ConnectionLimit, &r.ValidUntil, &r.MemberOf); err != nil {
+			log.Printf("Error scanning role: %v", err)
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return roles, nil
+}
+
+const scramIterations = 4096
+
+// ScramSHA256 hashes a password the way the server stores it with
+// password_encryption = scram-sha-256, so the plain text password never
+// reaches the server or its logs. The password is used as is, without
+// SASLprep normalization.
+func ScramSHA256(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	salted := pbkdf2.Key([]byte(password), salt, scramIterations, sha256.Size, sha256.New)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(salted, "Server Key")
+
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", scramIterations, b64(salt), b64(storedKey[:]), b64(serverKey)), nil
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+func roleOptions(attrs RoleAttributes) string {
+	flag := func(set bool, name string) string {
+		if set {
+			return name
+		}
+		return "NO" + name
+	}
+
+	options := []string{
+		flag(attrs.Superuser, "SUPERUSER"),
+		flag(attrs.CreateDB, "CREATEDB"),
+		flag(attrs.CreateRole, "CREATEROLE"),
+		flag(attrs.Login, "LOGIN"),
+		flag(attrs.Replication, "REPLICATION"),
+		"CONNECTION LIMIT " + strconv.Itoa(attrs.ConnectionLimit),
+	}
+	if attrs.ValidUntil != "" {
+		options = append(options, "VALID UNTIL "+quoteLiteral(attrs.ValidUntil))
+	}
+	return strings.Join(options, " ")
+}
+
+// CreateRoleDDL builds a CREATE ROLE statement. A non-empty password is
+// hashed client side before it is put into the statement.
+func CreateRoleDDL(name string, attrs RoleAttributes, password string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("role name cannot be empty")
+	}
+
+	ddl := fmt.Sprintf("CREATE ROLE %s WITH %s", pgx.Identifier{name}.Sanitize(), roleOptions(attrs))
+
+	if password != "" {
+		hash, err := ScramSHA256(password)
+		if err != nil {
+			return "", err
+		}
+		ddl += " PASSWORD " + quoteLiteral(hash)
+	}
+	return ddl, nil
+}
+
+// AlterRoleDDL changes the attributes of role that differ in attrs, so a
+// role with CREATEROLE but not SUPERUSER can alter the attributes it is
+// allowed to. An empty ValidUntil makes the password valid forever.
+func AlterRoleDDL(role Role, attrs RoleAttributes) (string, error) {
+	flag := func(set bool, name string) string {
+		if set {
+			return name
+		}
+		return "NO" + name
+	}
+
+	var options []string
+	for _, f := range []struct {
+		current, set bool
+		name         string
+	}{
+		{role.Superuser, attrs.Superuser, "SUPERUSER"},
+		{role.CreateDB, attrs.CreateDB, "CREATEDB"},
+		{role.CreateRole, attrs.CreateRole, "CREATEROLE"},
+		{role.Login, attrs.Login, "LOGIN"},
+		{role.Replication, attrs.Replication, "REPLICATION"},
+	} {
+		if f.current != f.set {
+			options = append(options, flag(f.set, f.name))
+		}
+	}
+	if role.ConnectionLimit != attrs.ConnectionLimit {
+		options = append(options, "CONNECTION LIMIT "+strconv.Itoa(attrs.ConnectionLimit))
+	}
+	if role.ValidUntil != attrs.ValidUntil {
+		validUntil := attrs.ValidUntil
+		if validUntil == "" {
+			validUntil = "infinity"
+		}
+		options = append(options, "VALID UNTIL "+quoteLiteral(validUntil))
+	}
+
+	if len(options) == 0 {
+		return "", fmt.Errorf("no attribute of %s was changed", role.Name)
+	}
+	return fmt.Sprintf("ALTER ROLE %s WITH %s", pgx.Identifier{role.Name}.Sanitize(), strings.Join(options, " ")), nil
+}
+
+func SetPasswordDDL(name, password string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	hash, err := ScramSHA256(password)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ALTER ROLE %s WITH PASSWORD %s", pgx.Identifier{name}.Sanitize(), quoteLiteral(hash)), nil
+}
+
+func GrantRoleDDL(role, member string) string {
+	return fmt.Sprintf("GRANT %s TO %s", pgx.Identifier{role}.Sanitize(), pgx.Identifier{member}.Sanitize())
+}
+
+func RevokeRoleDDL(role, member string) string {
+	return fmt.Sprintf("REVOKE %s FROM %s", pgx.Identifier{role}.Sanitize(), pgx.Identifier{member}.Sanitize())
+}
+
+// DropRoleDDL drops a role. When reassignTo is set, objects the role owns in
+// the current database are handed over to reassignTo and its remaining
+// privileges are dropped first, which DROP ROLE otherwise refuses to do.
+func DropRoleDDL(name, reassignTo string) string {
+	role := pgx.Identifier{name}.Sanitize()
+	if reassignTo == "" {
+		return "DROP ROLE " + role
+	}
+	return fmt.Sprintf("REASSIGN OWNED BY %s TO %s;\nDROP OWNED BY %s;\nDROP ROLE %s",
+		role, pgx.Identifier{reassignTo}.Sanitize(), role, role)
+}
@@ -0,0 +1,589 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Kinds of schema objects, in the order changes to them are listed.
+const (
+	ObjectTable      = "table"
+	ObjectColumn     = "column"
+	ObjectConstraint = "constraint"
+	ObjectIndex      = "index"
+	ObjectView       = "view"
+	ObjectFunction   = "function"
+)
+
+var objectKinds = []string{ObjectTable, ObjectColumn, ObjectConstraint, ObjectIndex, ObjectView, ObjectFunction}
+
+// Kinds of schema changes, from the point of view of the database being
+// migrated: an added object exists only in the target.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+	// ChangeRecreated is a view that is the same on both sides, but has to
+	// be dropped and created again because something it uses changes
+	ChangeRecreated = "recreated"
+)
+
+// SchemaObject is one object of a schema snapshot. Objects with the same
+// kind and name are the same object in two databases, and differ if their
+// definitions do.
+type SchemaObject struct {
+	Kind       string
+	Name       string
+	Definition string
+
+	// table is the table a column, constraint or index belongs to, or the
+	// table or view itself, as regclass prints it. deps are the tables a
+	// table inherits from, or the relations, columns and functions a view
+	// uses, named the same way.
+	table      string
+	deps       []string
+	columns    string
+	column     columnAttrs
+	foreignKey bool
+	create     string
+	drop       string
+	// replace changes the object in place, otherwise a change drops and
+	// creates it again
+	replace string
+}
+
+// SchemaChange is an object that differs between two snapshots. From is
+// empty for added objects and To for removed ones.
+type SchemaChange struct {
+	Kind   string
+	Name   string
+	Change string
+	From   SchemaObject
+	To     SchemaObject
+
+	// recreate drops and creates a changed view again instead of
+	// replacing it
+	recreate bool
+}
+
+// GetSchemaSnapshot introspects the tables, columns, constraints, indexes,
+// views and functions of a schema for DiffSchemas.
+func GetSchemaSnapshot(conn *pgxpool.Pool, schema string) ([]SchemaObject, error) {
+	var objects []SchemaObject
+	err := withCatalogSearchPath(conn, func(q catalogQuerier) error {
+		for _, snapshot := range []func(catalogQuerier, string) ([]SchemaObject, error){
+			tableObjects, constraintObjects, indexObjects, viewObjects, functionObjects,
+		} {
+			o, err := snapshot(q, schema)
+			if err != nil {
+				return err
+			}
+			objects = append(objects, o...)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error introspecting schema %s: %v", schema, err)
+		return nil, err
+	}
+	return objects, nil
+}
+
+// tableObjects returns the tables with their columns. Tables only differ
+// by existence, a change to one shows up as changes to its columns. Only
+// columns a table defines itself are listed, inherited ones change with
+// the parent.
+func tableObjects(q catalogQuerier, schema string) ([]SchemaObject, error) {
+	tables, err := getTableInfos(q, schema, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sql := `SELECT a.attrelid, ` + columnAttrsSQL + `
+		` + columnAttrsFrom + `
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped AND a.attislocal
+		ORDER BY a.attrelid, a.attnum`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching columns: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[uint32][]columnAttrs{}
+	for rows.Next() {
+		var table uint32
+		var c columnAttrs
+		if err := rows.Scan(append([]interface{}{&table}, c.fields()...)...); err != nil {
+			log.Printf("Error scanning columns: %v", err)
+			return nil, err
+		}
+		columns[table] = append(columns[table], c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	var objects []SchemaObject
+	for _, t := range tables {
+		var definitions []string
+		for _, c := range columns[t.oid] {
+			definitions = append(definitions, "    "+c.definition())
+			objects = append(objects, SchemaObject{
+				Kind:       ObjectColumn,
+				Name:       t.relname + "." + c.name,
+				Definition: c.definition(),
+				table:      t.name,
+				column:     c,
+				create:     fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", t.name, c.definition()),
+				drop:       fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", t.name, c.name),
+			})
+		}
+		create := t.createStatement(definitions)
+		objects = append(objects, SchemaObject{
+			Kind:       ObjectTable,
+			Name:       t.relname,
+			Definition: create,
+			table:      t.name,
+			deps:       t.parents,
+			create:     create,
+			drop:       fmt.Sprintf("DROP TABLE %s;", t.name),
+		})
+	}
+	return objects, nil
+}
+
+func constraintObjects(q catalogQuerier, schema string) ([]SchemaObject, error) {
+	sql := `SELECT c.relname, c.oid::regclass::text, quote_ident(con.conname), con.contype = 'f', pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND con.contype IN ('p', 'u', 'c', 'x', 'f') AND con.conislocal
+		ORDER BY c.relname, con.conname`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching constraints: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []SchemaObject
+	for rows.Next() {
+		var tableName, table, name, def string
+		var foreignKey bool
+		if err := rows.Scan(&tableName, &table, &name, &foreignKey, &def); err != nil {
+			log.Printf("Error scanning constraints: %v", err)
+			return nil, err
+		}
+		objects = append(objects, SchemaObject{
+			Kind:       ObjectConstraint,
+			Name:       tableName + "." + name,
+			Definition: def,
+			table:      table,
+			foreignKey: foreignKey,
+			create:     fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", table, name, def),
+			drop:       fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", table, name),
+		})
+	}
+	return objects, rows.Err()
+}
+
+func indexObjects(q catalogQuerier, schema string) ([]SchemaObject, error) {
+	sql := `SELECT c.oid::regclass::text, ic.relname, pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.conrelid = i.indrelid)
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits h WHERE h.inhrelid = i.indexrelid)
+		ORDER BY ic.relname`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching indexes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []SchemaObject
+	for rows.Next() {
+		var table, name, def string
+		if err := rows.Scan(&table, &name, &def); err != nil {
+			log.Printf("Error scanning indexes: %v", err)
+			return nil, err
+		}
+		objects = append(objects, SchemaObject{
+			Kind:       ObjectIndex,
+			Name:       name,
+			Definition: def,
+			table:      table,
+			create:     def + ";",
+			drop:       fmt.Sprintf("DROP INDEX %s;", qualifiedName(schema, name)),
+		})
+	}
+	return objects, rows.Err()
+}
+
+func viewObjects(q catalogQuerier, schema string) ([]SchemaObject, error) {
+	sql := `SELECT c.relname, c.oid::regclass::text, c.relkind = 'm', pg_get_viewdef(c.oid),
+			ARRAY(SELECT quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod)
+				FROM pg_attribute a WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum),
+			ARRAY(SELECT DISTINCT CASE WHEN d.refclassid = 'pg_proc'::regclass THEN d.refobjid::regprocedure::text
+					WHEN d.refobjsubid > 0 THEN d.refobjid::regclass::text || '.' || quote_ident(a.attname)
+					ELSE d.refobjid::regclass::text END
+				FROM pg_rewrite rw
+				JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = rw.oid
+				LEFT JOIN pg_attribute a ON d.refclassid = 'pg_class'::regclass
+					AND a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+				WHERE rw.ev_class = c.oid AND d.refclassid IN ('pg_class'::regclass, 'pg_proc'::regclass)
+					AND d.refobjid <> c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('v', 'm')
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		ORDER BY c.relname`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching views: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []SchemaObject
+	for rows.Next() {
+		var name, qualified, def string
+		var materialized bool
+		var columns, deps []string
+		if err := rows.Scan(&name, &qualified, &materialized, &def, &columns, &deps); err != nil {
+			log.Printf("Error scanning views: %v", err)
+			return nil, err
+		}
+		def = strings.TrimRight(strings.TrimSpace(def), ";")
+		o := SchemaObject{
+			Kind:       ObjectView,
+			Name:       name,
+			Definition: def,
+			table:      qualified,
+			deps:       deps,
+			columns:    strings.Join(columns, ", "),
+		}
+		if materialized {
+			o.create = fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s;", qualified, def)
+			o.drop = fmt.Sprintf("DROP MATERIALIZED VIEW %s;", qualified)
+		} else {
+			o.create = fmt.Sprintf("CREATE VIEW %s AS\n%s;", qualified, def)
+			o.replace = fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s;", qualified, def)
+			o.drop = fmt.Sprintf("DROP VIEW %s;", qualified)
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+func functionObjects(q catalogQuerier, schema string) ([]SchemaObject, error) {
+	sql := `SELECT p.oid::regprocedure::text, pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1 AND p.prokind IN ('f', 'p', 'w')
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY 1`
+	rows, err := q.Query(context.Background(), sql, schema)
+	if err != nil {
+		log.Printf("Error fetching functions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []SchemaObject
+	for rows.Next() {
+		var signature, def string
+		if err := rows.Scan(&signature, &def); err != nil {
+			log.Printf("Error scanning functions: %v", err)
+			return nil, err
+		}
+		def = strings.TrimRight(def, "\n")
+		objects = append(objects, SchemaObject{
+			Kind:       ObjectFunction,
+			Name:       signature,
+			Definition: def,
+			// pg_get_functiondef already says CREATE OR REPLACE
+			create:  def + ";",
+			replace: def + ";",
+			drop:    fmt.Sprintf("DROP ROUTINE %s;", signature),
+		})
+	}
+	return objects, rows.Err()
+}
+
+// DiffSchemas lists the objects that differ between two snapshots, by
+// kind and then by name. The columns of a table that is added or removed
+// go with it, as do the constraints and indexes of a removed table.
+func DiffSchemas(from, to []SchemaObject) []SchemaChange {
+	key := func(o SchemaObject) string { return o.Kind + " " + o.Name }
+	fromObjects, toObjects := map[string]SchemaObject{}, map[string]SchemaObject{}
+	for _, o := range from {
+		fromObjects[key(o)] = o
+	}
+	for _, o := range to {
+		toObjects[key(o)] = o
+	}
+
+	// Tables that exist on one side only
+	addedTables, removedTables := map[string]bool{}, map[string]bool{}
+	for k, o := range fromObjects {
+		if _, ok := toObjects[k]; !ok && o.Kind == ObjectTable {
+			removedTables[o.table] = true
+		}
+	}
+	for k, o := range toObjects {
+		if _, ok := fromObjects[k]; !ok && o.Kind == ObjectTable {
+			addedTables[o.table] = true
+		}
+	}
+
+	var changes []SchemaChange
+	for k, f := range fromObjects {
+		t, ok := toObjects[k]
+		switch {
+		case !ok:
+			if f.Kind != ObjectTable && removedTables[f.table] {
+				continue
+			}
+			changes = append(changes, SchemaChange{Kind: f.Kind, Name: f.Name, Change: ChangeRemoved, From: f})
+		case f.Kind != ObjectTable && f.Definition != t.Definition:
+			changes = append(changes, SchemaChange{Kind: f.Kind, Name: f.Name, Change: ChangeChanged, From: f, To: t})
+		}
+	}
+	for k, t := range toObjects {
+		if _, ok := fromObjects[k]; ok || (t.Kind == ObjectColumn && addedTables[t.table]) {
+			continue
+		}
+		changes = append(changes, SchemaChange{Kind: t.Kind, Name: t.Name, Change: ChangeAdded, To: t})
+	}
+
+	recreate := viewsToRecreate(changes, fromObjects, toObjects, key)
+	for i, c := range changes {
+		if c.Kind == ObjectView && recreate[c.From.table] {
+			changes[i].recreate = true
+			delete(recreate, c.From.table)
+		}
+	}
+	for _, f := range fromObjects {
+		if f.Kind == ObjectView && recreate[f.table] {
+			changes = append(changes, SchemaChange{Kind: f.Kind, Name: f.Name, Change: ChangeRecreated,
+				From: f, To: toObjects[key(f)], recreate: true})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return indexOfKind(a.Kind) < indexOfKind(b.Kind)
+		}
+		return a.Name < b.Name
+	})
+	return changes
+}
+
+// viewsToRecreate returns the views on both sides that can't be replaced
+// in place: those whose columns change, and those that use a relation,
+// column or function that is dropped or whose type changes. The views
+// using those are recreated too.
+func viewsToRecreate(changes []SchemaChange, from, to map[string]SchemaObject, key func(SchemaObject) string) map[string]bool {
+	dropped := map[string]bool{}
+	for _, c := range changes {
+		switch {
+		case c.Change == ChangeRemoved && c.Kind == ObjectColumn:
+			dropped[c.From.table+"."+c.From.column.name] = true
+		case c.Change == ChangeRemoved && c.Kind == ObjectFunction:
+			dropped[c.From.Name] = true
+		case c.Change == ChangeRemoved && (c.Kind == ObjectTable || c.Kind == ObjectView):
+			dropped[c.From.table] = true
+		case c.Change == ChangeChanged && c.Kind == ObjectColumn && changesColumnType(c.From.column, c.To.column):
+			dropped[c.From.table+"."+c.From.column.name] = true
+		}
+	}
+
+	recreate := map[string]bool{}
+	for found := true; found; {
+		found = false
+		for _, f := range from {
+			t, ok := to[key(f)]
+			if f.Kind != ObjectView || !ok || recreate[f.table] {
+				continue
+			}
+			changed := f.Definition != t.Definition && (t.replace == "" || f.replace == "" || f.columns != t.columns)
+			for _, dep := range f.deps {
+				changed = changed || dropped[dep] || recreate[dep]
+			}
+			if changed {
+				recreate[f.table] = true
+				found = true
+			}
+		}
+	}
+	return recreate
+}
+
+func indexOfKind(kind string) int {
+	for i, k := range objectKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(objectKinds)
+}
+
+// regeneratesColumn tells whether a change to a column's generation
+// expression drops and adds it again, since its values are computed anyway.
+func regeneratesColumn(f, t columnAttrs) bool {
+	return f.generated != t.generated || (t.generated != "" && f.defaultExpr != t.defaultExpr)
+}
+
+// changesColumnType tells whether a change to a column rewrites it, which
+// the server doesn't allow while a view uses it.
+func changesColumnType(f, t columnAttrs) bool {
+	return f.typeName != t.typeName || f.collation != t.collation || regeneratesColumn(f, t)
+}
+
+// alterColumn changes a column in place to match its definition in to.
+func alterColumn(from, to SchemaObject) []string {
+	f, t := from.column, to.column
+	if regeneratesColumn(f, t) {
+		return []string{from.drop, to.create}
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", to.table, t.name)
+	var statements []string
+	if f.typeName != t.typeName || f.collation != t.collation {
+		statements = append(statements, fmt.Sprintf("%s TYPE %s%s USING %s::%s;", alter, t.typeName, t.collation, t.name, t.typeName))
+	}
+	if f.identity == "" && f.defaultExpr != t.defaultExpr {
+		if t.defaultExpr == "" {
+			statements = append(statements, alter+" DROP DEFAULT;")
+		} else if t.identity == "" {
+			statements = append(statements, alter+" SET DEFAULT "+t.defaultExpr+";")
+		}
+	}
+	if f.identity != t.identity {
+		identities := map[string]string{"a": "ALWAYS", "d": "BY DEFAULT"}
+		switch {
+		case t.identity == "":
+			statements = append(statements, alter+" DROP IDENTITY;")
+			if t.defaultExpr != "" {
+				statements = append(statements, alter+" SET DEFAULT "+t.defaultExpr+";")
+			}
+		case f.identity == "":
+			statements = append(statements, alter+" ADD GENERATED "+identities[t.identity]+" AS IDENTITY;")
+		default:
+			statements = append(statements, alter+" SET GENERATED "+identities[t.identity]+";")
+		}
+	}
+	if f.notNull != t.notNull {
+		if t.notNull {
+			statements = append(statements, alter+" SET NOT NULL;")
+		} else {
+			statements = append(statements, alter+" DROP NOT NULL;")
+		}
+	}
+	return statements
+}
+
+// inDependencyOrder puts each change to a table or view after the changes
+// to the relations it depends on, keeping the order of everything else.
+func inDependencyOrder(changes []SchemaChange) []SchemaChange {
+	byName := map[string]SchemaChange{}
+	var names []string
+	deps := map[string][]string{}
+	for _, c := range changes {
+		o := c.To
+		if c.Change == ChangeRemoved {
+			o = c.From
+		}
+		name := c.Kind + " " + c.Name
+		if c.Kind == ObjectTable || c.Kind == ObjectView {
+			name = o.table
+		}
+		byName[name] = c
+		names = append(names, name)
+		deps[name] = o.deps
+	}
+
+	ordered := make([]SchemaChange, 0, len(changes))
+	for _, name := range dependencyOrder(names, deps) {
+		ordered = append(ordered, byName[name])
+	}
+	return ordered
+}
+
+// MigrationScript turns the database changes were computed from into the
+// other one, in a transaction. Everything that goes away is dropped first,
+// dependents before what they depend on, then objects are created or
+// changed with functions and tables before what uses them.
+func MigrationScript(fromName, toName string, changes []SchemaChange) string {
+	drops := make([][]string, len(objectKinds)+1)
+	creates := make([][]string, len(objectKinds)+1)
+	// Foreign keys are dropped before the keys they reference and added
+	// once every table and key exists
+	var foreignKeyDrops, foreignKeys []string
+	for _, c := range inDependencyOrder(changes) {
+		i := indexOfKind(c.Kind)
+		switch {
+		case c.Change == ChangeRemoved && c.From.foreignKey:
+			foreignKeyDrops = append(foreignKeyDrops, c.From.drop)
+		case c.Change == ChangeRemoved:
+			drops[i] = append(drops[i], c.From.drop)
+		case c.Change == ChangeAdded && c.To.foreignKey:
+			foreignKeys = append(foreignKeys, c.To.create)
+		case c.Change == ChangeAdded:
+			creates[i] = append(creates[i], c.To.create)
+		case c.Kind == ObjectColumn:
+			creates[i] = append(creates[i], alterColumn(c.From, c.To)...)
+		case c.To.replace != "" && !c.recreate:
+			creates[i] = append(creates[i], c.To.replace)
+		case c.To.foreignKey:
+			foreignKeyDrops = append(foreignKeyDrops, c.From.drop)
+			foreignKeys = append(foreignKeys, c.To.create)
+		default:
+			drops[i] = append(drops[i], c.From.drop)
+			creates[i] = append(creates[i], c.To.create)
+		}
+	}
+
+	script := []string{fmt.Sprintf("-- Migrates %s to %s\n\nBEGIN;", fromName, toName)}
+	add := func(statements []string) {
+		if len(statements) > 0 {
+			script = append(script, strings.Join(statements, "\n"))
+		}
+	}
+	// Dependents go before what they depend on
+	slices.Reverse(drops[indexOfKind(ObjectView)])
+	slices.Reverse(drops[indexOfKind(ObjectTable)])
+	add(drops[indexOfKind(ObjectView)])
+	add(foreignKeyDrops)
+	for _, kind := range []string{ObjectIndex, ObjectConstraint, ObjectColumn, ObjectTable, ObjectFunction} {
+		add(drops[indexOfKind(kind)])
+	}
+	for _, kind := range []string{ObjectFunction, ObjectTable, ObjectColumn, ObjectConstraint} {
+		add(creates[indexOfKind(kind)])
+	}
+	add(foreignKeys)
+	for _, kind := range []string{ObjectIndex, ObjectView} {
+		add(creates[indexOfKind(kind)])
+	}
+	if len(changes) == 0 {
+		script = append(script, "-- The schemas are the same")
+	}
+	script = append(script, "COMMIT;")
+	return strings.Join(script, "\n\n") + "\n"
+}
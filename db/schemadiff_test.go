@@ -0,0 +1,265 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tableObject(name string, parents ...string) SchemaObject {
+	return SchemaObject{
+		Kind:       ObjectTable,
+		Name:       name,
+		Definition: "CREATE TABLE public." + name + " ();",
+		table:      "public." + name,
+		deps:       parents,
+		create:     "CREATE TABLE public." + name + " ();",
+		drop:       "DROP TABLE public." + name + ";",
+	}
+}
+
+func columnObject(table string, c columnAttrs) SchemaObject {
+	return SchemaObject{
+		Kind:       ObjectColumn,
+		Name:       table + "." + c.name,
+		Definition: c.definition(),
+		table:      "public." + table,
+		column:     c,
+		create:     "ALTER TABLE public." + table + " ADD COLUMN " + c.definition() + ";",
+		drop:       "ALTER TABLE public." + table + " DROP COLUMN " + c.name + ";",
+	}
+}
+
+func viewObject(name, def, columns string, deps ...string) SchemaObject {
+	return SchemaObject{
+		Kind:       ObjectView,
+		Name:       name,
+		Definition: def,
+		table:      "public." + name,
+		deps:       deps,
+		columns:    columns,
+		create:     "CREATE VIEW public." + name + " AS " + def + ";",
+		replace:    "CREATE OR REPLACE VIEW public." + name + " AS " + def + ";",
+		drop:       "DROP VIEW public." + name + ";",
+	}
+}
+
+// changeSummary lists changes as "change kind name", marking the views
+// that are dropped and created again.
+func changeSummary(changes []SchemaChange) []string {
+	var summary []string
+	for _, c := range changes {
+		line := c.Change + " " + c.Kind + " " + c.Name
+		if c.recreate {
+			line += " (recreate)"
+		}
+		summary = append(summary, line)
+	}
+	return summary
+}
+
+func TestDiffSchemas(t *testing.T) {
+	id := columnAttrs{name: "id", typeName: "integer", notNull: true}
+	bigID := columnAttrs{name: "id", typeName: "bigint", notNull: true}
+	name := columnAttrs{name: "name", typeName: "text"}
+
+	tests := []struct {
+		name     string
+		from, to []SchemaObject
+		want     []string
+	}{
+		{
+			name: "same",
+			from: []SchemaObject{tableObject("t"), columnObject("t", id)},
+			to:   []SchemaObject{tableObject("t"), columnObject("t", id)},
+		},
+		{
+			name: "added table brings its columns",
+			to:   []SchemaObject{tableObject("t"), columnObject("t", id)},
+			want: []string{"added table t"},
+		},
+		{
+			name: "removed table takes its columns",
+			from: []SchemaObject{tableObject("t"), columnObject("t", id), columnObject("t", name)},
+			want: []string{"removed table t"},
+		},
+		{
+			name: "sorted by kind then name",
+			from: []SchemaObject{tableObject("t"), columnObject("t", id)},
+			to:   []SchemaObject{tableObject("t"), columnObject("t", bigID), columnObject("t", name), tableObject("a")},
+			want: []string{"added table a", "changed column t.id", "added column t.name"},
+		},
+		{
+			name: "changed view keeping its columns is replaced",
+			from: []SchemaObject{viewObject("v", "SELECT 1 AS a", "a integer")},
+			to:   []SchemaObject{viewObject("v", "SELECT 2 AS a", "a integer")},
+			want: []string{"changed view v"},
+		},
+		{
+			name: "changed view columns recreate it and its dependents",
+			from: []SchemaObject{
+				viewObject("v", "SELECT 1 AS a", "a integer"),
+				viewObject("w", "SELECT a FROM v", "a integer", "public.v", "public.v.a"),
+			},
+			to: []SchemaObject{
+				viewObject("v", "SELECT 1 AS a, 2 AS b", "a integer, b integer"),
+				viewObject("w", "SELECT a FROM v", "a integer", "public.v", "public.v.a"),
+			},
+			want: []string{"changed view v (recreate)", "recreated view w (recreate)"},
+		},
+		{
+			name: "column type change recreates the views using it",
+			from: []SchemaObject{
+				tableObject("t"), columnObject("t", id), columnObject("t", name),
+				viewObject("ids", "SELECT id FROM t", "id integer", "public.t", "public.t.id"),
+				viewObject("names", "SELECT name FROM t", "name text", "public.t", "public.t.name"),
+			},
+			to: []SchemaObject{
+				tableObject("t"), columnObject("t", bigID), columnObject("t", name),
+				viewObject("ids", "SELECT id FROM t", "id bigint", "public.t", "public.t.id"),
+				viewObject("names", "SELECT name FROM t", "name text", "public.t", "public.t.name"),
+			},
+			want: []string{"changed column t.id", "recreated view ids (recreate)"},
+		},
+		{
+			name: "view that stops using a dropped column is recreated",
+			from: []SchemaObject{
+				tableObject("t"), columnObject("t", id), columnObject("t", name),
+				viewObject("v", "SELECT id, name FROM t", "id integer, name text", "public.t", "public.t.id", "public.t.name"),
+			},
+			to: []SchemaObject{
+				tableObject("t"), columnObject("t", id),
+				viewObject("v", "SELECT id, NULL::text AS name FROM t", "id integer, name text", "public.t", "public.t.id"),
+			},
+			want: []string{"removed column t.name", "changed view v (recreate)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeSummary(DiffSchemas(tt.from, tt.to))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffSchemas() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationScript(t *testing.T) {
+	id := columnAttrs{name: "id", typeName: "integer"}
+	bigID := columnAttrs{name: "id", typeName: "bigint"}
+
+	tests := []struct {
+		name     string
+		from, to []SchemaObject
+		want     []string
+	}{
+		{
+			name: "same",
+			want: []string{"-- The schemas are the same"},
+		},
+		{
+			name: "parents are created first and dropped last",
+			from: []SchemaObject{tableObject("old_child", "public.old_parent"), tableObject("old_parent")},
+			to:   []SchemaObject{tableObject("a_child", "public.z_parent"), tableObject("z_parent")},
+			want: []string{
+				"DROP TABLE public.old_child;\nDROP TABLE public.old_parent;",
+				"CREATE TABLE public.z_parent ();\nCREATE TABLE public.a_child ();",
+			},
+		},
+		{
+			name: "views are recreated around a column type change",
+			from: []SchemaObject{
+				tableObject("t"), columnObject("t", id),
+				viewObject("v", "SELECT id FROM t", "id integer", "public.t", "public.t.id"),
+				viewObject("a", "SELECT id FROM v", "id integer", "public.v", "public.v.id"),
+			},
+			to: []SchemaObject{
+				tableObject("t"), columnObject("t", bigID),
+				viewObject("v", "SELECT id FROM t", "id bigint", "public.t", "public.t.id"),
+				viewObject("a", "SELECT id FROM v", "id bigint", "public.v", "public.v.id"),
+			},
+			want: []string{
+				"DROP VIEW public.a;\nDROP VIEW public.v;",
+				"ALTER TABLE public.t ALTER COLUMN id TYPE bigint USING id::bigint;",
+				"CREATE VIEW public.v AS SELECT id FROM t;\nCREATE VIEW public.a AS SELECT id FROM v;",
+			},
+		},
+		{
+			name: "changed view is replaced",
+			from: []SchemaObject{viewObject("v", "SELECT 1 AS a", "a integer")},
+			to:   []SchemaObject{viewObject("v", "SELECT 2 AS a", "a integer")},
+			want: []string{"CREATE OR REPLACE VIEW public.v AS SELECT 2 AS a;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := MigrationScript("a", "b", DiffSchemas(tt.from, tt.to))
+			want := "-- Migrates a to b\n\nBEGIN;\n\n" + strings.Join(tt.want, "\n\n") + "\n\nCOMMIT;\n"
+			if script != want {
+				t.Errorf("MigrationScript() =\n%s\nwant\n%s", script, want)
+			}
+		})
+	}
+}
+
+func TestAlterColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to columnAttrs
+		want     []string
+	}{
+		{
+			name: "type",
+			from: columnAttrs{name: "n", typeName: "integer"},
+			to:   columnAttrs{name: "n", typeName: "bigint"},
+			want: []string{"ALTER TABLE public.t ALTER COLUMN n TYPE bigint USING n::bigint;"},
+		},
+		{
+			name: "default and not null",
+			from: columnAttrs{name: "n", typeName: "integer", defaultExpr: "0"},
+			to:   columnAttrs{name: "n", typeName: "integer", notNull: true},
+			want: []string{
+				"ALTER TABLE public.t ALTER COLUMN n DROP DEFAULT;",
+				"ALTER TABLE public.t ALTER COLUMN n SET NOT NULL;",
+			},
+		},
+		{
+			name: "serial to identity",
+			from: columnAttrs{name: "id", typeName: "integer", defaultExpr: "nextval('t_id_seq'::regclass)"},
+			to:   columnAttrs{name: "id", typeName: "integer", identity: "d"},
+			want: []string{
+				"ALTER TABLE public.t ALTER COLUMN id DROP DEFAULT;",
+				"ALTER TABLE public.t ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY;",
+			},
+		},
+		{
+			name: "identity to default",
+			from: columnAttrs{name: "id", typeName: "integer", identity: "a"},
+			to:   columnAttrs{name: "id", typeName: "integer", defaultExpr: "1"},
+			want: []string{
+				"ALTER TABLE public.t ALTER COLUMN id DROP IDENTITY;",
+				"ALTER TABLE public.t ALTER COLUMN id SET DEFAULT 1;",
+			},
+		},
+		{
+			name: "generation expression",
+			from: columnAttrs{name: "g", typeName: "integer", defaultExpr: "a + 1", generated: "s"},
+			to:   columnAttrs{name: "g", typeName: "integer", defaultExpr: "a + 2", generated: "s"},
+			want: []string{
+				"ALTER TABLE public.t DROP COLUMN g;",
+				"ALTER TABLE public.t ADD COLUMN g integer GENERATED ALWAYS AS (a + 2) STORED;",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alterColumn(columnObject("t", tt.from), columnObject("t", tt.to))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alterColumn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
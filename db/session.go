@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// healthCheckPeriod is how often the pool closes idle connections the
+// server or a load balancer has dropped.
+const healthCheckPeriod = 15 * time.Second
+
+// SessionSettings are set on every connection of a pool. Empty settings keep
+// the server's defaults.
+type SessionSettings struct {
+	// SearchPath is a comma separated list of schemas. public is added at
+	// the end when missing, since the table views look tables up by their
+	// unqualified names in public.
+	SearchPath       string
+	Role             string
+	StatementTimeout string
+}
+
+func (s SessionSettings) statements() []string {
+	var statements []string
+	if s.SearchPath != "" {
+		var schemas []string
+		hasPublic := false
+		for _, schema := range strings.Split(s.SearchPath, ",") {
+			if schema = strings.TrimSpace(schema); schema != "" {
+				schemas = append(schemas, pgx.Identifier{schema}.Sanitize())
+				hasPublic = hasPublic || schema == "public"
+			}
+		}
+		if !hasPublic {
+			schemas = append(schemas, "public")
+		}
+		statements = append(statements, "SET search_path TO "+strings.Join(schemas, ", "))
+	}
+	if s.Role != "" {
+		statements = append(statements, "SET ROLE "+pgx.Identifier{s.Role}.Sanitize())
+	}
+	if s.StatementTimeout != "" {
+		statements = append(statements, "SET statement_timeout TO "+quoteLiteral(s.StatementTimeout))
+	}
+	return statements
+}
+
+func (s SessionSettings) apply(ctx context.Context, conn *pgx.Conn) error {
+	for _, statement := range s.statements() {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			log.Printf("Error applying session setting: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping checks that the server can be reached. A pool replaces broken
+// connections on its own, so a successful ping after a failed one means it
+// has reconnected.
+func Ping(conn *pgxpool.Pool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return conn.Ping(ctx)
+}
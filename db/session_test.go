@@ -0,0 +1,41 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSessionSettingsStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings SessionSettings
+		want     []string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:     "public is kept on the path",
+			settings: SessionSettings{SearchPath: "app"},
+			want:     []string{`SET search_path TO "app", public`},
+		},
+		{
+			name:     "public where the user put it",
+			settings: SessionSettings{SearchPath: " public , app,"},
+			want:     []string{`SET search_path TO "public", "app"`},
+		},
+		{
+			name:     "role and timeout",
+			settings: SessionSettings{Role: "reader", StatementTimeout: "30s"},
+			want:     []string{`SET ROLE "reader"`, `SET statement_timeout TO '30s'`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.statements(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Setting is a server configuration parameter from pg_settings.
+type Setting struct {
+	Name  string
+	Value string
+	Unit  string
+	// Type is bool, integer, real, string or enum; EnumValues lists the
+	// accepted values of an enum
+	Type        string
+	EnumValues  []string
+	Category    string
+	Description string
+	// Context tells when a change takes effect, e.g. postmaster settings
+	// need a restart and sighup ones a reload
+	Context        string
+	Source         string
+	BootValue      string
+	ResetValue     string
+	PendingRestart bool
+}
+
+// IsDefault reports whether the setting still has its built-in value.
+func (s Setting) IsDefault() bool {
+	return s.Source == "default" || s.Source == "override"
+}
+
+const ReloadConfSQL = "SELECT pg_reload_conf()"
+
+// GetSettings returns every setting ordered by category.
+func GetSettings(conn *pgxpool.Pool) ([]Setting, error) {
+	sql := `SELECT name, COALESCE(setting, ''), COALESCE(unit, ''), vartype, COALESCE(enumvals, '{}'), category, short_desc, context, source,
+			COALESCE(boot_val, ''), COALESCE(reset_val, ''), pending_restart
+		FROM pg_settings
+		ORDER BY category, name`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching settings: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []Setting
+	for rows.Next() {
+		var s Setting
+		if err := rows.Scan(&s.Name, &s.Value, &s.Unit, &s.Type, &s.EnumValues, &s.Category, &s.Description, &s.Context, &s.Source,
+			&s.BootValue, &s.ResetValue, &s.PendingRestart); err != nil {
+			log.Printf("Error scanning settings: %v", err)
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return settings, nil
+}
+
+// IsSuperuser reports whether the connected role is a superuser.
+func IsSuperuser(conn *pgxpool.Pool) (bool, error) {
+	var superuser bool
+	err := conn.QueryRow(context.Background(), "SELECT current_setting('is_superuser') = 'on'").Scan(&superuser)
+	if err != nil {
+		log.Printf("Error checking for superuser: %v", err)
+		return false, err
+	}
+	return superuser, nil
+}
+
+// listSettings are the settings whose elements the server quotes one by
+// one, so a single literal would become a single element. pg_settings
+// doesn't expose the flag, so this is the same list pg_dump keeps.
+var listSettings = map[string]bool{
+	"local_preload_libraries":   true,
+	"search_path":               true,
+	"session_preload_libraries": true,
+	"shared_preload_libraries":  true,
+	"temp_tablespaces":          true,
+	"unix_socket_directories":   true,
+}
+
+// AlterSystemSetDDL writes a setting to postgresql.auto.conf. It takes
+// effect on the next reload, or restart for postmaster settings. The
+// elements of a list setting are quoted separately.
+func AlterSystemSetDDL(name, value string) string {
+	elements := splitSettingList(value)
+	if !listSettings[strings.ToLower(name)] || len(elements) == 0 {
+		return fmt.Sprintf("ALTER SYSTEM SET %s = %s", settingName(name), quoteLiteral(value))
+	}
+
+	quoted := make([]string, len(elements))
+	for i, e := range elements {
+		quoted[i] = quoteLiteral(e)
+	}
+	return fmt.Sprintf("ALTER SYSTEM SET %s = %s", settingName(name), strings.Join(quoted, ", "))
+}
+
+// splitSettingList splits a list setting as the server shows it, where an
+// element with a comma or capitals is double-quoted, e.g. "$user", public.
+func splitSettingList(value string) []string {
+	var elements []string
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' && quoted && i+1 < len(value) && value[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			elements = append(elements, strings.TrimSpace(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if last := strings.TrimSpace(b.String()); last != "" || len(elements) > 0 {
+		elements = append(elements, last)
+	}
+	return elements
+}
+
+// AlterSystemResetDDL removes a setting from postgresql.auto.conf.
+func AlterSystemResetDDL(name string) string {
+	return "ALTER SYSTEM RESET " + settingName(name)
+}
+
+// settingName quotes a setting name, keeping the dot of extension settings
+// such as pg_stat_statements.max as a separator.
+func settingName(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
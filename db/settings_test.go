@@ -0,0 +1,57 @@
+package db
+
+import "testing"
+
+func TestAlterSystemSetDDL(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		value   string
+		want    string
+	}{
+		{
+			name:    "scalar",
+			setting: "work_mem",
+			value:   "64MB",
+			want:    `ALTER SYSTEM SET "work_mem" = '64MB'`,
+		},
+		{
+			name:    "scalar with a comma",
+			setting: "DateStyle",
+			value:   "ISO, MDY",
+			want:    `ALTER SYSTEM SET "DateStyle" = 'ISO, MDY'`,
+		},
+		{
+			name:    "list",
+			setting: "shared_preload_libraries",
+			value:   "pg_stat_statements, auto_explain",
+			want:    `ALTER SYSTEM SET "shared_preload_libraries" = 'pg_stat_statements', 'auto_explain'`,
+		},
+		{
+			name:    "list with quoted elements",
+			setting: "search_path",
+			value:   `"$user", public, "a,b"`,
+			want:    `ALTER SYSTEM SET "search_path" = '$user', 'public', 'a,b'`,
+		},
+		{
+			name:    "empty list",
+			setting: "shared_preload_libraries",
+			value:   "",
+			want:    `ALTER SYSTEM SET "shared_preload_libraries" = ''`,
+		},
+		{
+			name:    "extension setting",
+			setting: "pg_stat_statements.max",
+			value:   "10000",
+			want:    `ALTER SYSTEM SET "pg_stat_statements"."max" = '10000'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlterSystemSetDDL(tt.setting, tt.value); got != tt.want {
+				t.Errorf("AlterSystemSetDDL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
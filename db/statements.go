@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// StatementStat is a normalized query as tracked by pg_stat_statements.
+// Times are in milliseconds.
+type StatementStat struct {
+	Query      string
+	Calls      int64
+	TotalTime  float64
+	MeanTime   float64
+	Rows       int64
+	SharedHit  int64
+	SharedRead int64
+}
+
+// StatementSort is the column statements are ordered by, descending.
+type StatementSort int
+
+const (
+	SortByTotalTime StatementSort = iota
+	SortByMeanTime
+	SortByCalls
+	SortByRows
+	SortBySharedHit
+	SortBySharedRead
+)
+
+const CreateStatStatementsSQL = "CREATE EXTENSION pg_stat_statements"
+
+// ErrStatStatementsNotLoaded is returned when the extension is installed
+// but the server wasn't started with it in shared_preload_libraries.
+var ErrStatStatementsNotLoaded = errors.New("pg_stat_statements is not in shared_preload_libraries")
+
+// ResetStatStatementsSQL resets the statistics of the extension installed
+// in schema.
+func ResetStatStatementsSQL(schema string) string {
+	return "SELECT " + pgx.Identifier{schema, "pg_stat_statements_reset"}.Sanitize() + "()"
+}
+
+// StatStatementsSchema returns the schema pg_stat_statements is installed
+// in, or "" when it isn't installed in the current database.
+func StatStatementsSchema(conn *pgxpool.Pool) (string, error) {
+	var schema string
+	err := conn.QueryRow(context.Background(), `SELECT n.nspname FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname = 'pg_stat_statements'`).Scan(&schema)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error checking for pg_stat_statements: %v", err)
+		return "", err
+	}
+	return schema, nil
+}
+
+// statementOrder returns the column to sort by, given the names of the
+// time columns on this server.
+func statementOrder(sort StatementSort, totalTime, meanTime string) string {
+	switch sort {
+	case SortByMeanTime:
+		return meanTime
+	case SortByCalls:
+		return "calls"
+	case SortByRows:
+		return "rows"
+	case SortBySharedHit:
+		return "shared_blks_hit"
+	case SortBySharedRead:
+		return "shared_blks_read"
+	}
+	return totalTime
+}
+
+// GetStatementStats returns the top statements by sort run against the
+// current database from the extension installed in schema. PostgreSQL 13
+// renamed the time columns, so both names are handled.
+func GetStatementStats(conn *pgxpool.Pool, schema string, sort StatementSort) ([]StatementStat, error) {
+	var version int
+	err := conn.QueryRow(context.Background(), "SELECT current_setting('server_version_num')::int").Scan(&version)
+	if err != nil {
+		log.Printf("Error fetching server version: %v", err)
+		return nil, err
+	}
+	totalTime, meanTime := "total_exec_time", "mean_exec_time"
+	if version < 130000 {
+		totalTime, meanTime = "total_time", "mean_time"
+	}
+
+	sql := `SELECT query, calls, ` + totalTime + `, ` + meanTime + `, rows, shared_blks_hit, shared_blks_read
+		FROM ` + pgx.Identifier{schema, "pg_stat_statements"}.Sanitize() + `
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		ORDER BY ` + statementOrder(sort, totalTime, meanTime) + ` DESC
+		LIMIT 500`
+	rows, err := conn.Query(context.Background(), sql)
+	if err != nil {
+		log.Printf("Error fetching statement stats: %v", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "55000" {
+			return nil, ErrStatStatementsNotLoaded
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []StatementStat
+	for rows.Next() {
+		var s StatementStat
+		if err := rows.Scan(&s.Query, &s.Calls, &s.TotalTime, &s.MeanTime, &s.Rows, &s.SharedHit, &s.SharedRead); err != nil {
+			log.Printf("Error scanning statement stats: %v", err)
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return stats, nil
+}
+
+var paramPattern = regexp.MustCompile(`\$(\d+)`)
+
+// CountParams returns the highest $n placeholder in a normalized query.
+func CountParams(query string) int {
+	count := 0
+	for _, match := range paramPattern.FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(match[1])
+		count = max(count, n)
+	}
+	return count
+}
+
+// BindSampleParams replaces the $n placeholders with the given values as
+// untyped literals, so the server infers their types as it would for
+// literals written in the query. An empty value becomes NULL.
+func BindSampleParams(query string, params []string) string {
+	return paramPattern.ReplaceAllStringFunc(query, func(placeholder string) string {
+		n, _ := strconv.Atoi(placeholder[1:])
+		if n < 1 || n > len(params) {
+			return placeholder
+		}
+		if params[n-1] == "" {
+			return "NULL"
+		}
+		return quoteLiteral(params[n-1])
+	})
+}
+
+// Explain returns the plan the server would use for a query, without
+// running it.
+func Explain(conn *pgxpool.Pool, query string) ([]string, error) {
+	rows, err := conn.Query(context.Background(), "EXPLAIN "+query)
+	if err != nil {
+		log.Printf("Error explaining query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			log.Printf("Error scanning plan: %v", err)
+			return nil, err
+		}
+		plan = append(plan, line)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Error explaining query: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	return plan, nil
+}
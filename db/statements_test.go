@@ -0,0 +1,90 @@
+package db
+
+import "testing"
+
+func TestCountParams(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT * FROM t WHERE a = $1", 1},
+		{"SELECT * FROM t WHERE a = $2 AND b = $1", 2},
+		{"SELECT * FROM t WHERE a IN ($1, $10)", 10},
+	}
+
+	for _, tt := range tests {
+		if got := CountParams(tt.query); got != tt.want {
+			t.Errorf("CountParams(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBindSampleParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		params []string
+		want   string
+	}{
+		{
+			name:   "literals",
+			query:  "SELECT * FROM t WHERE a = $1 AND b = $2",
+			params: []string{"42", "x"},
+			want:   "SELECT * FROM t WHERE a = '42' AND b = 'x'",
+		},
+		{
+			name:   "empty is NULL",
+			query:  "SELECT * FROM t WHERE a = $1",
+			params: []string{""},
+			want:   "SELECT * FROM t WHERE a = NULL",
+		},
+		{
+			name:   "quotes and backslashes",
+			query:  "SELECT $1, $2",
+			params: []string{"it's", `a\b`},
+			want:   `SELECT 'it''s', E'a\\b'`,
+		},
+		{
+			name:   "two digit placeholder",
+			query:  "SELECT $1, $10",
+			params: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"},
+			want:   "SELECT '1', '10'",
+		},
+		{
+			name:   "missing value is left alone",
+			query:  "SELECT $1, $2",
+			params: []string{"1"},
+			want:   "SELECT '1', $2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BindSampleParams(tt.query, tt.params); got != tt.want {
+				t.Errorf("BindSampleParams() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementOrder(t *testing.T) {
+	tests := []struct {
+		sort StatementSort
+		want string
+	}{
+		{SortByTotalTime, "total_exec_time"},
+		{SortByMeanTime, "mean_exec_time"},
+		{SortByCalls, "calls"},
+		{SortByRows, "rows"},
+		{SortBySharedHit, "shared_blks_hit"},
+		{SortBySharedRead, "shared_blks_read"},
+		{StatementSort(99), "total_exec_time"},
+	}
+
+	for _, tt := range tests {
+		if got := statementOrder(tt.sort, "total_exec_time", "mean_exec_time"); got != tt.want {
+			t.Errorf("statementOrder(%d) = %q, want %q", tt.sort, got, tt.want)
+		}
+	}
+}
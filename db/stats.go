@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type TableStats struct {
+	TotalSize string
+	TableSize string
+	IndexSize string
+	ToastSize string
+
+	LiveTuples int64
+	DeadTuples int64
+
+	// The maintenance times are nil when it never ran
+	LastVacuum      *time.Time
+	LastAutovacuum  *time.Time
+	LastAnalyze     *time.Time
+	LastAutoanalyze *time.Time
+
+	SeqScans   int64
+	IndexScans int64
+	Updates    int64
+	HotUpdates int64
+
+	// BloatBytes estimates the space beyond what the rows need. It is nil
+	// until the table has been analyzed.
+	BloatBytes *int64
+	BloatSize  string
+}
+
+// GetTableStats reads a table's sizes and activity counters. Bloat is
+// estimated from the average row width in pg_stats and the fillfactor,
+// which is rough but needs no extension.
+func GetTableStats(conn *pgxpool.Pool, tableName string) (TableStats, error) {
+	sql := `WITH t AS (
+			SELECT c.oid, c.relpages, c.reltuples, c.reltoastrelid,
+				current_setting('block_size')::float8 AS block_size,
+				COALESCE((SELECT sum(st.avg_width) FROM pg_stats st
+					WHERE st.schemaname = n.nspname AND st.tablename = c.relname), 0) AS row_width,
+				COALESCE((SELECT o.option_value::float8 FROM pg_options_to_table(c.reloptions) o
+					WHERE o.option_name = 'fillfactor'), 100) AS fillfactor
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.oid = $1::regclass
+		), b AS (
+			SELECT t.*, CASE WHEN t.reltuples > 0 AND t.row_width > 0 THEN
+				GREATEST(t.relpages - ceil(t.reltuples * (28 + t.row_width) / (t.block_size * t.fillfactor / 100)), 0) * t.block_size
+			END AS bloat
+			FROM t
+		)
+		SELECT pg_size_pretty(pg_total_relation_size(b.oid)), pg_size_pretty(pg_relation_size(b.oid)),
+			pg_size_pretty(pg_indexes_size(b.oid)),
+			pg_size_pretty(COALESCE(pg_total_relation_size(NULLIF(b.reltoastrelid, 0)), 0)),
+			s.n_live_tup, s.n_dead_tup, s.last_vacuum, s.last_autovacuum, s.last_analyze, s.last_autoanalyze,
+			s.seq_scan, COALESCE(s.idx_scan, 0), s.n_tup_upd, s.n_tup_hot_upd,
+			b.bloat::bigint, COALESCE(pg_size_pretty(b.bloat::bigint), '')
+		FROM b JOIN pg_stat_user_tables s ON s.relid = b.oid`
+
+	var s TableStats
+	err := conn.QueryRow(context.Background(), sql, pgx.Identifier{tableName}.Sanitize()).Scan(
+		&s.TotalSize, &s.TableSize, &s.IndexSize, &s.ToastSize,
+		&s.LiveTuples, &s.DeadTuples,
+		&s.LastVacuum, &s.LastAutovacuum, &s.LastAnalyze, &s.LastAutoanalyze,
+		&s.SeqScans, &s.IndexScans, &s.Updates, &s.HotUpdates,
+		&s.BloatBytes, &s.BloatSize,
+	)
+	if err != nil {
+		log.Printf("Error fetching table stats: %v", err)
+		return TableStats{}, err
+	}
+	return s, nil
+}
+
+func VacuumSQL(tableName string) string {
+	return "VACUUM (ANALYZE, VERBOSE) " + pgx.Identifier{tableName}.Sanitize()
+}
+
+func AnalyzeSQL(tableName string) string {
+	return "ANALYZE VERBOSE " + pgx.Identifier{tableName}.Sanitize()
+}
+
+// ExecWithNotices runs sql on a connection of its own, passing every
+// notice the server sends, such as VERBOSE output, to onNotice as it
+// arrives. Pooled connections can't have their notice handler changed.
+func ExecWithNotices(ctx context.Context, conn *pgxpool.Pool, sql string, onNotice func(string)) error {
+	poolConfig := conn.Config()
+	config := poolConfig.ConnConfig
+	config.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) {
+		onNotice(n.Severity + ": " + n.Message)
+	}
+
+	c, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		log.Printf("Error while making a connection: %v", err)
+		return err
+	}
+	defer c.Close(context.Background())
+
+	if poolConfig.AfterConnect != nil {
+		if err := poolConfig.AfterConnect(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.Exec(ctx, sql); err != nil {
+		log.Printf("Error executing %q: %v", sql, err)
+		return err
+	}
+	return nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
+	"regexp"
 	"strings"
 )
 
-func GetTables(conn *pgx.Conn) ([]string, error) {
+func GetTables(conn *pgxpool.Pool) ([]string, error) {
 	cursor, err := conn.Query(context.Background(), "SELECT tablename FROM pg_tables WHERE schemaname = 'public'")
 	defer cursor.Close()
 
@@ -31,9 +33,146 @@ func GetTables(conn *pgx.Conn) ([]string, error) {
 	return tables, nil
 }
 
-func CreateTable(conn *pgx.Conn, tableName string, schema string) error {
-	query := fmt.Sprintf("CREATE TABLE %s (%s)", pgx.Identifier{tableName}.Sanitize(), schema)
-	_, err := conn.Exec(context.Background(), query)
+// ColumnDef describes a column for CreateTableDDL. Type is a bare type name
+// such as "varchar" or "numeric"; Size holds its modifier ("255" or "10,2").
+// Default is a single SQL expression, checked by validateExpression.
+type ColumnDef struct {
+	Name       string
+	Type       string
+	Size       string
+	Array      bool
+	Nullable   bool
+	Default    string
+	PrimaryKey bool
+	Unique     bool
+	RefTable   string
+	RefColumn  string
+}
+
+var sizePattern = regexp.MustCompile(`^\d+(,\s*\d+)?$`)
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9 ]*$`)
+
+// CreateTableDDL builds a CREATE TABLE statement from column definitions,
+// quoting every identifier.
+func CreateTableDDL(tableName string, columns []ColumnDef) (string, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table name cannot be empty")
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("a table needs at least one column")
+	}
+
+	var lines []string
+	var primaryKey []string
+	for i, col := range columns {
+		if col.Name == "" {
+			return "", fmt.Errorf("column %d has no name", i+1)
+		}
+		def, err := columnTypeDDL(col)
+		if err != nil {
+			return "", err
+		}
+
+		line := fmt.Sprintf("%s %s", pgx.Identifier{col.Name}.Sanitize(), def)
+		if !col.Nullable && !col.PrimaryKey {
+			line += " NOT NULL"
+		}
+		if col.Default != "" {
+			if err := validateExpression(col.Default); err != nil {
+				return "", fmt.Errorf("default of column %s: %w", col.Name, err)
+			}
+			line += " DEFAULT " + col.Default
+		}
+		if col.Unique {
+			line += " UNIQUE"
+		}
+		if col.RefTable != "" {
+			line += " REFERENCES " + pgx.Identifier{col.RefTable}.Sanitize()
+			if col.RefColumn != "" {
+				line += fmt.Sprintf(" (%s)", pgx.Identifier{col.RefColumn}.Sanitize())
+			}
+		}
+		if col.PrimaryKey {
+			primaryKey = append(primaryKey, pgx.Identifier{col.Name}.Sanitize())
+		}
+		lines = append(lines, line)
+	}
+
+	if len(primaryKey) > 0 {
+		lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", pgx.Identifier{tableName}.Sanitize(), strings.Join(lines, ",\n    ")), nil
+}
+
+func columnTypeDDL(col ColumnDef) (string, error) {
+	if !typePattern.MatchString(col.Type) {
+		return "", fmt.Errorf("column %s has an invalid type %q", col.Name, col.Type)
+	}
+
+	def := col.Type
+	if col.Size != "" {
+		if !sizePattern.MatchString(col.Size) {
+			return "", fmt.Errorf("column %s has an invalid size %q", col.Name, col.Size)
+		}
+		def += fmt.Sprintf("(%s)", col.Size)
+	}
+	if col.Array {
+		def += "[]"
+	}
+	return def, nil
+}
+
+// validateExpression makes sure user input meant as one expression, such
+// as a default, is one: quotes and parentheses must balance, and there may
+// be no statement separator, comment or dollar quote outside a quoted
+// string, so nothing can follow it in the statement.
+func validateExpression(expr string) error {
+	depth := 0
+	var quote rune
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			if c == quote {
+				if i+1 < len(runes) && runes[i+1] == quote {
+					i++
+				} else {
+					quote = 0
+				}
+			}
+			continue
+		}
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses in %q", expr)
+			}
+		case c == ';', c == '$', c == '-' && next == '-', c == '/' && next == '*':
+			return fmt.Errorf("%q must be a single expression", expr)
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return fmt.Errorf("unbalanced quotes or parentheses in %q", expr)
+	}
+	return nil
+}
+
+func CreateTable(conn *pgxpool.Pool, tableName string, columns []ColumnDef) error {
+	query, err := CreateTableDDL(tableName, columns)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(context.Background(), query)
 
 	if err != nil {
 		log.Printf("Error while creating table: %v", err)
@@ -44,11 +183,29 @@ func CreateTable(conn *pgx.Conn, tableName string, schema string) error {
 	return nil
 }
 
-func GetTableData(conn *pgx.Conn, tableName string) ([]map[string]interface{}, error) {
+// RowFilter restricts table data to rows whose Columns equal Values.
+type RowFilter struct {
+	Columns []string
+	Values  []interface{}
+}
+
+func (f RowFilter) Empty() bool {
+	return len(f.Columns) == 0
+}
+
+func GetTableData(conn *pgxpool.Pool, tableName string, filter RowFilter) ([]map[string]interface{}, error) {
 	// Sanitize the table name to prevent SQL injection
 	sql := fmt.Sprintf("SELECT * FROM %s", pgx.Identifier{tableName}.Sanitize())
 
-	rows, err := conn.Query(context.Background(), sql)
+	if !filter.Empty() {
+		conditions := make([]string, len(filter.Columns))
+		for i, col := range filter.Columns {
+			conditions[i] = fmt.Sprintf("%s = $%d", pgx.Identifier{col}.Sanitize(), i+1)
+		}
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := conn.Query(context.Background(), sql, filter.Values...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +240,7 @@ func GetTableData(conn *pgx.Conn, tableName string) ([]map[string]interface{}, e
 	return data, nil
 }
 
-func GetTableColumns(conn *pgx.Conn, tableName string) ([]string, error) {
+func GetTableColumns(conn *pgxpool.Pool, tableName string) ([]string, error) {
 	sql := fmt.Sprintf("SELECT column_name FROM information_schema.columns WHERE table_name='%s'", tableName)
 	rows, err := conn.Query(context.Background(), sql)
 	if err != nil {
@@ -107,7 +264,7 @@ func GetTableColumns(conn *pgx.Conn, tableName string) ([]string, error) {
 	return columns, nil
 }
 
-func InsertRow(conn *pgx.Conn, tableName string, values map[string]interface{}) error {
+func InsertRow(conn *pgxpool.Pool, tableName string, values map[string]interface{}) error {
 	columns := make([]string, 0, len(values))
 	placeholders := make([]string, 0, len(values))
 	args := make([]interface{}, 0, len(values))
@@ -124,3 +281,133 @@ func InsertRow(conn *pgx.Conn, tableName string, values map[string]interface{})
 	_, err := conn.Exec(context.Background(), sql, args...)
 	return err
 }
+
+type Column struct {
+	Name       string
+	Type       string
+	PrimaryKey bool
+	NotNull    bool
+	Default    string
+	Comment    string
+}
+
+func GetColumnInfo(conn *pgxpool.Pool, tableName string) ([]Column, error) {
+	sql := `SELECT a.attname, format_type(a.atttypid, a.atttypmod), i.indrelid IS NOT NULL, a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), COALESCE(col_description(a.attrelid, a.attnum), '')
+		FROM pg_attribute a
+		LEFT JOIN pg_index i ON i.indrelid = a.attrelid AND i.indisprimary AND a.attnum = ANY(i.indkey)
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error querying column info: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var col Column
+		if err := rows.Scan(&col.Name, &col.Type, &col.PrimaryKey, &col.NotNull, &col.Default, &col.Comment); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return columns, nil
+}
+
+func DropTableDDL(tableName string, cascade bool) string {
+	ddl := "DROP TABLE " + pgx.Identifier{tableName}.Sanitize()
+	if cascade {
+		ddl += " CASCADE"
+	}
+	return ddl
+}
+
+func DropTable(conn *pgxpool.Pool, tableName string, cascade bool) error {
+	_, err := conn.Exec(context.Background(), DropTableDDL(tableName, cascade))
+
+	if err != nil {
+		log.Printf("Error while dropping table: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully dropped table: %v", tableName)
+	return nil
+}
+
+func TruncateTableDDL(tableName string, restartIdentity, cascade bool) string {
+	ddl := "TRUNCATE TABLE " + pgx.Identifier{tableName}.Sanitize()
+	if restartIdentity {
+		ddl += " RESTART IDENTITY"
+	}
+	if cascade {
+		ddl += " CASCADE"
+	}
+	return ddl
+}
+
+func TruncateTable(conn *pgxpool.Pool, tableName string, restartIdentity, cascade bool) error {
+	_, err := conn.Exec(context.Background(), TruncateTableDDL(tableName, restartIdentity, cascade))
+
+	if err != nil {
+		log.Printf("Error while truncating table: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully truncated table: %v", tableName)
+	return nil
+}
+
+func RenameTable(conn *pgxpool.Pool, tableName, newName string) error {
+	ddl, err := RenameTableDDL(tableName, newName)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(context.Background(), ddl)
+
+	if err != nil {
+		log.Printf("Error while renaming table: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully renamed table %v to %v", tableName, newName)
+	return nil
+}
+
+// GetDependentObjects describes the objects that depend on a table and would
+// block a plain DROP TABLE, or be dropped along with it by CASCADE: views,
+// foreign keys in other tables and the like.
+func GetDependentObjects(conn *pgxpool.Pool, tableName string) ([]string, error) {
+	sql := `SELECT DISTINCT pg_describe_object(d.classid, d.objid, 0)
+		FROM pg_depend d
+		WHERE d.refclassid = 'pg_class'::regclass AND d.refobjid = $1::regclass AND d.deptype = 'n'
+		ORDER BY 1`
+	rows, err := conn.Query(context.Background(), sql, pgx.Identifier{tableName}.Sanitize())
+	if err != nil {
+		log.Printf("Error querying dependent objects: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return objects, nil
+}
@@ -2,12 +2,12 @@ package db
 
 import (
 	"context"
-	"fmt"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
+	"net/url"
 )
 
-func GetUsers(conn *pgx.Conn) ([]string, error) {
+func GetUsers(conn *pgxpool.Pool) ([]string, error) {
 	cursor, err := conn.Query(context.Background(), "SELECT usename FROM pg_catalog.pg_user")
 	defer cursor.Close()
 
@@ -32,9 +32,12 @@ func GetUsers(conn *pgx.Conn) ([]string, error) {
 	return users, nil
 }
 
-func CreateUser(conn *pgx.Conn, username string, password string) error {
-	sqlQuery := fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", pgx.Identifier{username}.Sanitize(), password)
-	_, err := conn.Exec(context.Background(), sqlQuery)
+func CreateUser(conn *pgxpool.Pool, username string, password string) error {
+	sqlQuery, err := CreateRoleDDL(username, RoleAttributes{Login: true, ConnectionLimit: -1}, password)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(context.Background(), sqlQuery)
 
 	if err != nil {
 		log.Printf("Error while creating user: %v", err)
@@ -45,9 +48,19 @@ func CreateUser(conn *pgx.Conn, username string, password string) error {
 	return nil
 }
 
-func ConnectAsUser(username string, password string, database string) (*pgx.Conn, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@localhost/%s", username, password, database)
-	conn, err := pgx.Connect(context.Background(), connStr)
+// ConnectAsUser opens a connection pool. Every connection it opens, also
+// the ones replacing lost connections, gets settings applied first.
+func ConnectAsUser(username string, password string, database string, settings SessionSettings) (*pgxpool.Pool, error) {
+	connURL := url.URL{Scheme: "postgres", User: url.UserPassword(username, password), Host: "localhost", Path: "/" + database}
+	config, err := pgxpool.ParseConfig(connURL.String())
+	if err != nil {
+		log.Printf("Error while making a connection: %v", err)
+		return nil, err
+	}
+	config.HealthCheckPeriod = healthCheckPeriod
+	config.AfterConnect = settings.apply
+
+	conn, err := pgxpool.ConnectConfig(context.Background(), config)
 
 	if err != nil {
 		log.Printf("Error while making a connection: %v", err)
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"lazysql/db"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ddlMsg struct{ ddl string }
+type ddlWrittenMsg struct {
+	ddl  string
+	path string
+}
+
+func fetchTableDDL(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		ddl, err := db.GetTableDDL(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return ddlMsg{ddl: ddl}
+	}
+}
+
+func exportSchema(conn *pgxpool.Pool, schema, path string) tea.Cmd {
+	return func() tea.Msg {
+		ddl, err := db.GetSchemaDDL(conn, schema)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return writeDDL(ddl, path)()
+	}
+}
+
+func writeDDL(ddl, path string) tea.Cmd {
+	return func() tea.Msg {
+		if err := os.WriteFile(path, []byte(ddl), 0o644); err != nil {
+			return errMsg{err: err}
+		}
+		return ddlWrittenMsg{ddl: ddl, path: path}
+	}
+}
+
+// showDDL switches to the DDL view, going back to returnState on esc.
+func (m *Model) showDDL(title, ddl string, returnState State) {
+	m.ddlTitle = title
+	m.ddlView.SetContent(ddl)
+	m.ddlView.GotoTop()
+	m.ddlReturnState = returnState
+	m.err = nil
+	m.state = StateDDL
+}
+
+func (m *Model) openTableDDL(tableName string) tea.Cmd {
+	m.selectedTable = tableName
+	m.showDDL("DDL of "+tableName, "Loading...", StateListTables)
+	return fetchTableDDL(m.dbConn, tableName)
+}
+
+func (m *Model) openExportSchema() {
+	m.openForm(formExportSchema, "Export the DDL of a schema", StateListTables,
+		formField{prompt: "Schema", value: "public"},
+		formField{prompt: "File", placeholder: "<database>-<schema>.sql"})
+}
+
+// exportWithFormParams writes the schema from the export form to its file
+// and shows what was written.
+func (m *Model) exportWithFormParams() tea.Cmd {
+	schema, path := m.formValue(0), m.formValue(1)
+	if schema == "" {
+		m.err = fmt.Errorf("a schema is required")
+		return nil
+	}
+	if path == "" {
+		path = fmt.Sprintf("%s-%s.sql", m.selectedDB, schema)
+	}
+	m.showDDL(fmt.Sprintf("Exporting schema %s to %s", schema, path), "Loading...", StateListTables)
+	return exportSchema(m.dbConn, schema, path)
+}
+
+func (m *Model) updateDDL(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.ddlView, cmd = m.ddlView.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case ddlMsg:
+		m.ddlView.SetContent(highlightSQL(msg.ddl))
+	case ddlWrittenMsg:
+		m.ddlTitle = "Written to " + msg.path
+		m.ddlView.SetContent(highlightSQL(msg.ddl))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = m.ddlReturnState
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
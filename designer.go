@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Types offered by the designer's type picker. Types listed in sizedTypes
+// accept a size such as varchar(255) or numeric(10,2).
+var designerTypes = []string{
+	"integer", "bigint", "smallint", "serial", "bigserial",
+	"numeric", "real", "double precision", "boolean",
+	"text", "varchar", "char",
+	"date", "timestamp", "timestamptz", "time", "interval",
+	"uuid", "json", "jsonb", "bytea", "inet",
+}
+
+var sizedTypes = map[string]bool{"varchar": true, "char": true, "numeric": true}
+
+const (
+	designerFieldName = iota
+	designerFieldType
+	designerFieldSize
+	designerFieldArray
+	designerFieldNullable
+	designerFieldDefault
+	designerFieldPrimaryKey
+	designerFieldUnique
+	designerFieldRefTable
+	designerFieldRefColumn
+	designerFieldCount
+)
+
+var designerHeaders = []struct {
+	title string
+	width int
+}{
+	{"Name", 16},
+	{"Type", 17},
+	{"Size", 6},
+	{"[]", 3},
+	{"Null", 4},
+	{"Default", 14},
+	{"PK", 3},
+	{"Uniq", 4},
+	{"References", 16},
+	{"Ref Column", 12},
+}
+
+var focusedFieldStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("36")).Bold(true).Underline(true)
+
+type designerColumn struct {
+	name       textinput.Model
+	typeIndex  int
+	size       textinput.Model
+	array      bool
+	nullable   bool
+	defaultVal textinput.Model
+	primaryKey bool
+	unique     bool
+	// refTable indexes m.tables, offset by one so that 0 means no reference
+	refTable  int
+	refColumn textinput.Model
+}
+
+func newDesignerInput(width int) textinput.Model {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Width = width - 1
+	return input
+}
+
+func newDesignerColumn() designerColumn {
+	col := designerColumn{
+		name:       newDesignerInput(designerHeaders[designerFieldName].width),
+		size:       newDesignerInput(designerHeaders[designerFieldSize].width),
+		nullable:   true,
+		defaultVal: newDesignerInput(designerHeaders[designerFieldDefault].width),
+		refColumn:  newDesignerInput(designerHeaders[designerFieldRefColumn].width),
+	}
+	col.name.Placeholder = "column"
+	col.refColumn.Placeholder = "id"
+	return col
+}
+
+// initTableDesigner starts the designer with a single serial primary key,
+// which is what most tables begin with.
+func (m *Model) initTableDesigner() {
+	id := newDesignerColumn()
+	id.name.SetValue("id")
+	id.typeIndex = indexOf(designerTypes, "serial")
+	id.nullable = false
+	id.primaryKey = true
+
+	m.designerColumns = []designerColumn{id, newDesignerColumn()}
+	m.designerRow = 1
+	m.designerField = designerFieldName
+	m.focusDesignerField()
+}
+
+// indexOf returns the position of item in items, or -1 if it isn't there.
+func indexOf(items []string, item string) int {
+	for i, it := range items {
+		if it == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// focusDesignerField blurs every input and focuses the one under the cursor.
+func (m *Model) focusDesignerField() {
+	for i := range m.designerColumns {
+		col := &m.designerColumns[i]
+		col.name.Blur()
+		col.size.Blur()
+		col.defaultVal.Blur()
+		col.refColumn.Blur()
+	}
+	if input := m.designerInput(); input != nil {
+		input.Focus()
+	}
+}
+
+// designerInput returns the text input under the cursor, or nil when the
+// cursor is on a picker or toggle.
+func (m *Model) designerInput() *textinput.Model {
+	col := &m.designerColumns[m.designerRow]
+	switch m.designerField {
+	case designerFieldName:
+		return &col.name
+	case designerFieldSize:
+		return &col.size
+	case designerFieldDefault:
+		return &col.defaultVal
+	case designerFieldRefColumn:
+		return &col.refColumn
+	}
+	return nil
+}
+
+func (m *Model) moveDesignerCursor(delta int) {
+	pos := m.designerRow*designerFieldCount + m.designerField + delta
+	total := len(m.designerColumns) * designerFieldCount
+	pos = (pos%total + total) % total
+	m.designerRow = pos / designerFieldCount
+	m.designerField = pos % designerFieldCount
+	m.focusDesignerField()
+}
+
+// cycleDesignerPicker steps the type or reference picker under the cursor.
+func (m *Model) cycleDesignerPicker(delta int) bool {
+	col := &m.designerColumns[m.designerRow]
+	switch m.designerField {
+	case designerFieldType:
+		col.typeIndex = (col.typeIndex + delta + len(designerTypes)) % len(designerTypes)
+		if !sizedTypes[designerTypes[col.typeIndex]] {
+			col.size.SetValue("")
+		}
+		return true
+	case designerFieldRefTable:
+		n := len(m.tables) + 1
+		col.refTable = (col.refTable + delta + n) % n
+		return true
+	}
+	return false
+}
+
+// toggleDesignerField flips the checkbox under the cursor.
+func (m *Model) toggleDesignerField() bool {
+	col := &m.designerColumns[m.designerRow]
+	switch m.designerField {
+	case designerFieldArray:
+		col.array = !col.array
+	case designerFieldNullable:
+		col.nullable = !col.nullable
+	case designerFieldPrimaryKey:
+		col.primaryKey = !col.primaryKey
+		if col.primaryKey {
+			col.nullable = false
+		}
+	case designerFieldUnique:
+		col.unique = !col.unique
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *Model) updateTableDesigner(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if input := m.designerInput(); input != nil {
+			var cmd tea.Cmd
+			*input, cmd = input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		m.err = nil
+		m.state = StateListTables
+		return nil
+	case "tab":
+		m.moveDesignerCursor(1)
+		return nil
+	case "shift+tab":
+		m.moveDesignerCursor(-1)
+		return nil
+	case "up":
+		m.moveDesignerCursor(-designerFieldCount)
+		return nil
+	case "down":
+		m.moveDesignerCursor(designerFieldCount)
+		return nil
+	case "ctrl+n":
+		m.designerColumns = append(m.designerColumns, newDesignerColumn())
+		m.designerRow = len(m.designerColumns) - 1
+		m.designerField = designerFieldName
+		m.focusDesignerField()
+		return nil
+	case "ctrl+d":
+		if len(m.designerColumns) > 1 {
+			m.designerColumns = append(m.designerColumns[:m.designerRow], m.designerColumns[m.designerRow+1:]...)
+			m.designerRow = min(m.designerRow, len(m.designerColumns)-1)
+			m.focusDesignerField()
+		}
+		return nil
+	case "ctrl+s":
+		columns := m.designerColumnDefs()
+		if _, err := db.CreateTableDDL(m.tableName, columns); err != nil {
+			m.err = err
+			return nil
+		}
+		m.err = nil
+		m.state = StateListTables
+		return createTable(m.dbConn, m.tableName, columns)
+	case "left", "right":
+		delta := 1
+		if keyMsg.String() == "left" {
+			delta = -1
+		}
+		if m.cycleDesignerPicker(delta) {
+			return nil
+		}
+	case " ":
+		if m.toggleDesignerField() || m.cycleDesignerPicker(1) {
+			return nil
+		}
+	case "enter":
+		if !m.toggleDesignerField() {
+			m.moveDesignerCursor(1)
+		}
+		return nil
+	}
+
+	if input := m.designerInput(); input != nil {
+		var cmd tea.Cmd
+		*input, cmd = input.Update(msg)
+		return cmd
+	}
+	return nil
+}
+
+func (m *Model) designerColumnDefs() []db.ColumnDef {
+	columns := make([]db.ColumnDef, len(m.designerColumns))
+	for i, col := range m.designerColumns {
+		def := db.ColumnDef{
+			Name:       strings.TrimSpace(col.name.Value()),
+			Type:       designerTypes[col.typeIndex],
+			Array:      col.array,
+			Nullable:   col.nullable,
+			Default:    strings.TrimSpace(col.defaultVal.Value()),
+			PrimaryKey: col.primaryKey,
+			Unique:     col.unique,
+		}
+		if sizedTypes[def.Type] {
+			def.Size = strings.TrimSpace(col.size.Value())
+		}
+		if col.refTable > 0 && col.refTable <= len(m.tables) {
+			def.RefTable = m.tables[col.refTable-1]
+			def.RefColumn = strings.TrimSpace(col.refColumn.Value())
+		}
+		columns[i] = def
+	}
+	return columns
+}
+
+func checkbox(checked bool) string {
+	if checked {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+func (m *Model) tableDesignerView() string {
+	var b strings.Builder
+
+	cell := func(field int, content string, focused bool) {
+		width := designerHeaders[field].width
+		style := lipgloss.NewStyle().Width(width).MaxWidth(width).Inline(true)
+		if focused {
+			content = focusedFieldStyle.Render(content)
+		}
+		b.WriteString(style.Render(content))
+		b.WriteString(" ")
+	}
+
+	b.WriteString("  ")
+	for field, h := range designerHeaders {
+		cell(field, detailTypeStyle.Render(h.title), false)
+	}
+	b.WriteString("\n")
+
+	for row, col := range m.designerColumns {
+		cursor := "  "
+		if row == m.designerRow {
+			cursor = "> "
+		}
+		b.WriteString(cursor)
+
+		focused := func(field int) bool {
+			return row == m.designerRow && field == m.designerField
+		}
+		refTable := "-"
+		if col.refTable > 0 && col.refTable <= len(m.tables) {
+			refTable = m.tables[col.refTable-1]
+		}
+
+		cell(designerFieldName, col.name.View(), false)
+		cell(designerFieldType, "‹"+designerTypes[col.typeIndex]+"›", focused(designerFieldType))
+		cell(designerFieldSize, col.size.View(), false)
+		cell(designerFieldArray, checkbox(col.array), focused(designerFieldArray))
+		cell(designerFieldNullable, checkbox(col.nullable), focused(designerFieldNullable))
+		cell(designerFieldDefault, col.defaultVal.View(), false)
+		cell(designerFieldPrimaryKey, checkbox(col.primaryKey), focused(designerFieldPrimaryKey))
+		cell(designerFieldUnique, checkbox(col.unique), focused(designerFieldUnique))
+		cell(designerFieldRefTable, "‹"+refTable+"›", focused(designerFieldRefTable))
+		cell(designerFieldRefColumn, col.refColumn.View(), false)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	ddl, err := db.CreateTableDDL(m.tableName, m.designerColumnDefs())
+	if err != nil {
+		b.WriteString(detailTypeStyle.Render(fmt.Sprintf("-- %v", err)))
+	} else {
+		b.WriteString(detailTypeStyle.Render(ddl))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	detailNameStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("36")).Bold(true)
+	detailTypeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+)
+
+// initRecordView renders every column of a row vertically into the detail
+// viewport, so values that don't fit in a grid cell can be read in full.
+func (m *Model) initRecordView(row map[string]interface{}) {
+	width := m.recordView.Width
+	if width <= 0 {
+		width = 80
+	}
+	valueStyle := lipgloss.NewStyle().PaddingLeft(2).Width(width)
+
+	var b strings.Builder
+	for i, col := range m.dataColumns {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(detailNameStyle.Render(col.Name))
+		b.WriteString(" ")
+		b.WriteString(detailTypeStyle.Render(col.Type))
+		b.WriteString("\n")
+		b.WriteString(valueStyle.Render(formatDetailValue(col.Type, row[col.Name])))
+	}
+
+	m.recordView.SetContent(b.String())
+	m.recordView.GotoTop()
+}
+
+// formatDetailValue expands a value for the detail pane: JSON is
+// pretty-printed and binary data is shown as a hex dump.
+func formatDetailValue(dataType string, value interface{}) string {
+	if value == nil {
+		return nullStyle.Render("NULL")
+	}
+
+	switch baseType(dataType) {
+	case "json", "jsonb":
+		return formatJSON(value)
+	case "bytea":
+		if b, ok := value.([]byte); ok {
+			if len(b) == 0 {
+				return "(empty)"
+			}
+			return strings.TrimRight(hex.Dump(b), "\n")
+		}
+	}
+
+	return formatValue(dataType, value)
+}
+
+func formatJSON(value interface{}) string {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(out.String())
+}
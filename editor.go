@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// editTarget is what the text in $EDITOR is: a definition that replaces
+// the current one once the diff is accepted, or a query that runs as soon
+// as the editor closes.
+type editTarget int
+
+const (
+	editDefinition editTarget = iota
+	editQuery
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+)
+
+type definitionMsg struct{ ddl string }
+type editorClosedMsg struct{ err error }
+type definitionAppliedMsg struct{ ddl string }
+type queryResultMsg struct{ result db.QueryResult }
+
+func fetchDefinition(fetch func() (string, error)) tea.Cmd {
+	return func() tea.Msg {
+		ddl, err := fetch()
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return definitionMsg{ddl: ddl}
+	}
+}
+
+func applyDefinition(conn *pgxpool.Pool, ddl string) tea.Cmd {
+	return func() tea.Msg {
+		if err := db.ApplyDefinition(conn, ddl); err != nil {
+			return errMsg{err: err}
+		}
+		return definitionAppliedMsg{ddl: ddl}
+	}
+}
+
+func runQuery(conn *pgxpool.Pool, sql string) tea.Cmd {
+	return func() tea.Msg {
+		result, err := db.RunQuery(conn, sql)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return queryResultMsg{result: result}
+	}
+}
+
+// editorCommand runs $EDITOR, which may carry arguments such as "code -w",
+// falling back to vi.
+func editorCommand(path string) *exec.Cmd {
+	args := strings.Fields(os.Getenv("EDITOR"))
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// editDefinitionOf loads a definition and opens it in the editor, coming
+// back to returnState when done.
+func (m *Model) editDefinitionOf(title string, fetch func() (string, error), returnState State) tea.Cmd {
+	m.editTarget = editDefinition
+	m.editTitle = title
+	m.editReturnState = returnState
+	m.err = nil
+	return fetchDefinition(fetch)
+}
+
+// editQueryText opens the query editor, starting from the last query.
+func (m *Model) editQueryText() tea.Cmd {
+	m.editTarget = editQuery
+	m.editTitle = "Query"
+	m.editReturnState = StateListTables
+	m.editText = m.lastQuery
+	m.err = nil
+	return m.launchEditor()
+}
+
+// launchEditor hands the terminal to the editor with the text being edited
+// in a temporary file.
+func (m *Model) launchEditor() tea.Cmd {
+	f, err := os.CreateTemp("", "lazysql-*.sql")
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	defer f.Close()
+	if _, err := f.WriteString(m.editText); err != nil {
+		m.err = err
+		return nil
+	}
+	m.editFile = f.Name()
+
+	// The callback's message skips wrapCmd, so it's tagged here for the
+	// tab that opened the editor
+	id := m.id
+	return tea.ExecProcess(editorCommand(m.editFile), func(err error) tea.Msg {
+		return sessionMsg{id: id, msg: editorClosedMsg{err: err}}
+	})
+}
+
+// handleEditorMsg follows the editor flow whatever view started it.
+func (m *Model) handleEditorMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case definitionMsg:
+		m.editOriginal = msg.ddl
+		m.editText = msg.ddl
+		return m.launchEditor()
+	case editorClosedMsg:
+		content, err := os.ReadFile(m.editFile)
+		os.Remove(m.editFile)
+		if msg.err == nil {
+			msg.err = err
+		}
+		if msg.err != nil {
+			m.err = fmt.Errorf("editor: %w", msg.err)
+			return nil
+		}
+		m.editText = string(content)
+
+		if m.editTarget == editQuery {
+			m.lastQuery = m.editText
+			if strings.TrimSpace(m.editText) == "" {
+				return nil
+			}
+			m.queryResult = db.QueryResult{}
+			m.queryRunning = true
+			m.initQueryTable()
+			m.state = StateQueryResult
+			return runQuery(m.dbConn, m.editText)
+		}
+
+		if strings.TrimSpace(m.editText) == strings.TrimSpace(m.editOriginal) {
+			m.state = m.editReturnState
+			m.err = fmt.Errorf("no changes to %s", m.editTitle)
+			return nil
+		}
+		m.editDiffView.SetContent(renderDiff(m.editOriginal, m.editText))
+		m.editDiffView.GotoTop()
+		m.state = StateEditDiff
+	}
+	return nil
+}
+
+func (m *Model) updateEditDiff(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.editDiffView, cmd = m.editDiffView.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case definitionAppliedMsg:
+		m.err = nil
+		m.state = m.editReturnState
+		cmds = append(cmds, func() tea.Msg { return ddlExecutedMsg{ddl: msg.ddl} })
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "y":
+			cmds = append(cmds, applyDefinition(m.dbConn, m.editText))
+		case "e":
+			cmds = append(cmds, m.launchEditor())
+		case "esc":
+			m.err = nil
+			m.state = m.editReturnState
+		}
+	case errMsg:
+		// Stay on the diff so the error can be fixed with 'e'
+		m.err = msg.err
+	}
+
+	return cmds
+}
+
+func (m *Model) initQueryTable() {
+	widths := make([]int, len(m.queryResult.Columns))
+	for i, name := range m.queryResult.Columns {
+		widths[i] = len(name)
+	}
+	rows := make([]table.Row, len(m.queryResult.Rows))
+	for r, values := range m.queryResult.Rows {
+		for i, v := range values {
+			values[i] = strings.Join(strings.Fields(v), " ")
+			widths[i] = max(widths[i], min(lipgloss.Width(values[i]), 40))
+		}
+		rows[r] = values
+	}
+	columns := make([]table.Column, len(m.queryResult.Columns))
+	for i, name := range m.queryResult.Columns {
+		columns[i] = table.Column{Title: name, Width: widths[i]}
+	}
+
+	m.queryTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-12),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.queryTable.SetStyles(tableStyle)
+}
+
+func (m *Model) updateQueryResult(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.queryTable, cmd = m.queryTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case queryResultMsg:
+		m.err = nil
+		m.queryRunning = false
+		m.queryResult = msg.result
+		m.initQueryTable()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "e":
+			cmds = append(cmds, m.editQueryText())
+		case "esc":
+			m.err = nil
+			m.state = m.editReturnState
+		}
+	case errMsg:
+		// Stay on the results so the query can be fixed with 'e'
+		m.err = msg.err
+		m.queryRunning = false
+	}
+
+	return cmds
+}
+
+func (m *Model) queryResultView() string {
+	r := m.queryResult
+	switch {
+	case m.queryRunning:
+		return "Running..."
+	case m.err != nil:
+		return "The query failed."
+	case len(r.Columns) == 0:
+		return r.CommandTag
+	}
+	return fmt.Sprintf("%s\n\n%s", r.CommandTag, m.queryTable.View())
+}
+
+// renderDiff shows the changes from old to new line by line. Lines are
+// numbered as in the new text, which is what errors refer to.
+func renderDiff(old, new string) string {
+	a := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(new, "\n"), "\n")
+
+	// Longest common subsequence of lines, from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, fmt.Sprintf("%4d   %s", j+1, a[i]))
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, diffRemovedStyle.Render(fmt.Sprintf("     - %s", a[i])))
+			i++
+		default:
+			out = append(out, diffAddedStyle.Render(fmt.Sprintf("%4d + %s", j+1, b[j])))
+			j++
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+type viewsMsg struct{ views []string }
+
+func fetchViews(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		views, err := db.GetViews(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return viewsMsg{views: views}
+	}
+}
+
+func (m *Model) openViews() tea.Cmd {
+	m.viewList = m.newPickerList("Views", nil)
+	m.err = nil
+	m.state = StateViews
+	return fetchViews(m.dbConn)
+}
+
+func (m *Model) updateViews(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.viewList, cmd = m.viewList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case viewsMsg:
+		cmds = append(cmds, m.viewList.SetItems(convertToListItems(msg.views)))
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchViews(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "enter":
+			if selectedItem := m.viewList.SelectedItem(); selectedItem != nil {
+				conn, name := m.dbConn, selectedItem.(myListItem).title
+				cmds = append(cmds, m.editDefinitionOf("view "+name, func() (string, error) {
+					return db.GetViewDef(conn, name)
+				}, StateViews))
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
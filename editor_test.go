@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "unchanged",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: []string{"   1   a", "   2   b"},
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc",
+			new:  "a\nx\nc",
+			want: []string{"   1   a", "     - b", "   2 + x", "   3   c"},
+		},
+		{
+			name: "added and removed",
+			old:  "a\nb",
+			new:  "b\nc",
+			want: []string{"     - a", "   1   b", "   2 + c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderDiff(tt.old, tt.new)
+			if want := strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("renderDiff() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
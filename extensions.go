@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type extensionsMsg struct{ extensions []db.Extension }
+type extensionDependentsMsg struct {
+	name    string
+	objects []string
+}
+
+func fetchExtensions(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		extensions, err := db.GetExtensions(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return extensionsMsg{extensions: extensions}
+	}
+}
+
+func fetchExtensionDependents(conn *pgxpool.Pool, name string) tea.Cmd {
+	return func() tea.Msg {
+		objects, err := db.GetExtensionDependents(conn, name)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return extensionDependentsMsg{name: name, objects: objects}
+	}
+}
+
+func (m *Model) openExtensions() tea.Cmd {
+	m.extensions = nil
+	m.initExtensionTable()
+	m.err = nil
+	m.state = StateExtensions
+	return fetchExtensions(m.dbConn)
+}
+
+func (m *Model) initExtensionTable() {
+	columns := []table.Column{
+		{Title: "Extension", Width: 24},
+		{Title: "Installed", Width: 10},
+		{Title: "Default", Width: 10},
+		{Title: "Schema", Width: 16},
+		{Title: "Description", Width: max(m.windowSize.Width-4-60-10, 20)},
+	}
+
+	rows := make([]table.Row, len(m.extensions))
+	for i, e := range m.extensions {
+		rows[i] = table.Row{e.Name, e.InstalledVersion, e.DefaultVersion, e.Schema, e.Comment}
+	}
+
+	cursor := m.extensionTable.Cursor()
+	m.extensionTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.extensionTable.SetStyles(tableStyle)
+	m.extensionTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) selectedExtension() (db.Extension, bool) {
+	if len(m.extensions) == 0 {
+		return db.Extension{}, false
+	}
+	return m.extensions[m.extensionTable.Cursor()], true
+}
+
+// dropExtensionTitle previews what DROP EXTENSION ... CASCADE would take
+// with it.
+func dropExtensionTitle(name string, objects []string) string {
+	title := "Drop extension " + selectedStyle.Render(name)
+	if len(objects) == 0 {
+		return title + "\n\nNo objects outside the extension depend on it."
+	}
+	return title + "\n\nThese objects depend on it and are only dropped with CASCADE:\n  " +
+		strings.Join(objects, "\n  ")
+}
+
+func (m *Model) updateExtensions(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.extensionTable, cmd = m.extensionTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case extensionsMsg:
+		m.extensions = msg.extensions
+		m.initExtensionTable()
+	case extensionDependentsMsg:
+		m.openForm(formDropExtension, dropExtensionTitle(msg.name, msg.objects), StateExtensions,
+			formField{prompt: "CASCADE", options: yesNo})
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchExtensions(m.dbConn))
+	case tea.KeyMsg:
+		e, ok := m.selectedExtension()
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchExtensions(m.dbConn))
+		case "n", "enter":
+			if !ok {
+				break
+			}
+			if e.InstalledVersion != "" {
+				m.err = fmt.Errorf("%s is already installed", e.Name)
+				break
+			}
+			m.openForm(formCreateExtension, "Create extension "+e.Name, StateExtensions,
+				formField{prompt: "Schema", placeholder: "the extension's default"},
+				formField{prompt: "Version", value: e.DefaultVersion, options: e.Versions},
+				formField{prompt: "CASCADE (install required extensions)", options: yesNo})
+		case "u":
+			if !ok {
+				break
+			}
+			if e.InstalledVersion == "" {
+				m.err = fmt.Errorf("%s is not installed", e.Name)
+				break
+			}
+			m.openForm(formUpdateExtension, fmt.Sprintf("Update extension %s from version %s", e.Name, e.InstalledVersion), StateExtensions,
+				formField{prompt: "Version", value: e.DefaultVersion, options: e.Versions})
+		case "x":
+			if !ok {
+				break
+			}
+			if e.InstalledVersion == "" {
+				m.err = fmt.Errorf("%s is not installed", e.Name)
+				break
+			}
+			cmds = append(cmds, fetchExtensionDependents(m.dbConn, e.Name))
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
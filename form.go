@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// formAction identifies what a submitted form builds. Every form ends in a
+// DDL statement that is shown for confirmation before it runs, except the
+// EXPLAIN parameters form, whose query isn't executed, and the schema export
+// and migration forms, which write a file.
+type formAction int
+
+const (
+	formAddColumn formAction = iota
+	formRenameColumn
+	formAlterColumnType
+	formSetDefault
+	formSetComment
+	formRenameTable
+	formCreateIndex
+	formAddCheck
+	formAddForeignKey
+	formCreateRole
+	formAlterRole
+	formSetPassword
+	formGrantRole
+	formRevokeRole
+	formDropRole
+	formDefaultPrivileges
+	formCreateDatabase
+	formRenameDatabase
+	formDropDatabase
+	formExplainQuery
+	formAlterSystem
+	formCreateExtension
+	formUpdateExtension
+	formDropExtension
+	formExportSchema
+	formSaveMigration
+)
+
+// formField describes one form input. A field with options is a picker
+// cycled with the arrow keys instead of a free text input, a secret field
+// masks what is typed.
+type formField struct {
+	prompt      string
+	placeholder string
+	value       string
+	options     []string
+	secret      bool
+}
+
+var yesNo = []string{"no", "yes"}
+
+type ddlExecutedMsg struct{ ddl string }
+
+func execDDL(conn *pgxpool.Pool, ddl string) tea.Cmd {
+	return func() tea.Msg {
+		err := db.ExecDDL(conn, ddl)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return ddlExecutedMsg{ddl: ddl}
+	}
+}
+
+// openForm shows a form of text inputs. Esc returns to returnState, and so
+// does confirming or cancelling the resulting DDL.
+func (m *Model) openForm(action formAction, title string, returnState State, fields ...formField) {
+	m.formAction = action
+	m.formTitle = title
+	m.formReturnState = returnState
+	m.formInputs = make([]textinput.Model, len(fields))
+	m.formOptions = make([][]string, len(fields))
+	for i, f := range fields {
+		input := textinput.New()
+		input.Prompt = f.prompt + ": "
+		input.Placeholder = f.placeholder
+		if f.secret {
+			input.EchoMode = textinput.EchoPassword
+		}
+		value := f.value
+		if value == "" && len(f.options) > 0 {
+			value = f.options[0]
+		}
+		input.SetValue(value)
+		m.formInputs[i] = input
+		m.formOptions[i] = f.options
+	}
+	m.formIndex = 0
+	m.formInputs[0].Focus()
+	m.err = nil
+	m.state = StateForm
+}
+
+func (m *Model) formValue(i int) string {
+	return strings.TrimSpace(m.formInputs[i].Value())
+}
+
+func (m *Model) formBool(i int) bool {
+	return m.formValue(i) == "yes"
+}
+
+// cycleFormOption steps the picker under the cursor, if it is one.
+func (m *Model) cycleFormOption(delta int) bool {
+	options := m.formOptions[m.formIndex]
+	if len(options) == 0 {
+		return false
+	}
+	// A value that isn't one of the options counts as the first
+	current := max(indexOf(options, m.formValue(m.formIndex)), 0)
+	m.formInputs[m.formIndex].SetValue(options[(current+delta+len(options))%len(options)])
+	return true
+}
+
+func (m *Model) focusFormInput(i int) {
+	m.formInputs[m.formIndex].Blur()
+	m.formIndex = (i + len(m.formInputs)) % len(m.formInputs)
+	m.formInputs[m.formIndex].Focus()
+}
+
+func (m *Model) updateForm(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc":
+			m.err = nil
+			m.state = m.formReturnState
+			return nil
+		case "tab", "down":
+			m.focusFormInput(m.formIndex + 1)
+			return nil
+		case "shift+tab", "up":
+			m.focusFormInput(m.formIndex - 1)
+			return nil
+		case "enter":
+			if m.formIndex < len(m.formInputs)-1 {
+				m.focusFormInput(m.formIndex + 1)
+				return nil
+			}
+			switch m.formAction {
+			case formExplainQuery:
+				return m.explainWithFormParams()
+			case formExportSchema:
+				return m.exportWithFormParams()
+			case formSaveMigration:
+				return m.saveMigrationWithFormParams()
+			}
+			ddl, err := m.formDDL()
+			if err != nil {
+				m.err = err
+				return nil
+			}
+			m.confirmDDL(ddl, m.formReturnState)
+			return nil
+		case "left":
+			if m.cycleFormOption(-1) {
+				return nil
+			}
+		case "right", " ":
+			if m.cycleFormOption(1) {
+				return nil
+			}
+		}
+
+		// Pickers don't take typed input
+		if len(m.formOptions[m.formIndex]) > 0 {
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.formInputs[m.formIndex], cmd = m.formInputs[m.formIndex].Update(msg)
+	return cmd
+}
+
+// formDDL builds the statement for the submitted form.
+func (m *Model) formDDL() (string, error) {
+	switch m.formAction {
+	case formAddColumn:
+		return db.AddColumnDDL(m.selectedTable, m.formValue(0), m.formValue(1), m.formBool(2), m.formValue(3))
+	case formRenameColumn:
+		return db.RenameColumnDDL(m.selectedTable, m.selectedColumn().Name, m.formValue(0))
+	case formAlterColumnType:
+		return db.AlterColumnTypeDDL(m.selectedTable, m.selectedColumn().Name, m.formValue(0), m.formValue(1))
+	case formSetDefault:
+		return db.SetDefaultDDL(m.selectedTable, m.selectedColumn().Name, m.formValue(0))
+	case formSetComment:
+		return db.ColumnCommentDDL(m.selectedTable, m.selectedColumn().Name, m.formValue(0)), nil
+	case formRenameTable:
+		ddl, err := db.RenameTableDDL(m.selectedTable, m.formValue(0))
+		m.pendingTableRename = m.formValue(0)
+		return ddl, err
+	case formCreateIndex:
+		return db.CreateIndexDDL(m.selectedTable, db.IndexDef{
+			Name:         m.formValue(0),
+			Columns:      splitList(m.formValue(1)),
+			Method:       m.formValue(2),
+			Unique:       m.formBool(3),
+			Where:        m.formValue(4),
+			Concurrently: m.formBool(5),
+		})
+	case formAddCheck:
+		return db.AddCheckDDL(m.selectedTable, m.formValue(0), m.formValue(1), m.formBool(2))
+	case formAddForeignKey:
+		return db.AddForeignKeyDDL(m.selectedTable, db.ForeignKeyDef{
+			Name:       m.formValue(0),
+			Columns:    splitList(m.formValue(1)),
+			RefTable:   m.fkRefTable,
+			RefColumns: m.selectedFKRefColumns(),
+			OnDelete:   m.formValue(2),
+			OnUpdate:   m.formValue(3),
+			NotValid:   m.formBool(4),
+		})
+	case formCreateRole:
+		attrs, err := m.formRoleAttributes(2)
+		if err != nil {
+			return "", err
+		}
+		return db.CreateRoleDDL(m.formValue(0), attrs, m.formInputs[1].Value())
+	case formAlterRole:
+		attrs, err := m.formRoleAttributes(0)
+		if err != nil {
+			return "", err
+		}
+		return db.AlterRoleDDL(m.selectedRole(), attrs)
+	case formSetPassword:
+		if m.formInputs[0].Value() != m.formInputs[1].Value() {
+			return "", fmt.Errorf("passwords do not match")
+		}
+		return db.SetPasswordDDL(m.selectedRole().Name, m.formInputs[0].Value())
+	case formGrantRole:
+		return db.GrantRoleDDL(m.formValue(0), m.selectedRole().Name), nil
+	case formRevokeRole:
+		return db.RevokeRoleDDL(m.formValue(0), m.selectedRole().Name), nil
+	case formDropRole:
+		reassignTo := m.formValue(0)
+		if reassignTo == noReassign {
+			reassignTo = ""
+		}
+		return db.DropRoleDDL(m.selectedRole().Name, reassignTo), nil
+	case formDefaultPrivileges:
+		return db.AlterDefaultPrivilegesDDL(m.formValue(0), m.formValue(1), m.formValue(2) == "GRANT",
+			splitList(m.formValue(3)), m.formValue(4), m.formValue(5))
+	case formCreateDatabase:
+		return db.CreateDatabaseDDL(db.DatabaseDef{
+			Name:       m.formValue(0),
+			Owner:      m.formValue(1),
+			Template:   m.formValue(2),
+			Encoding:   m.formValue(3),
+			Locale:     m.formValue(4),
+			Tablespace: m.formValue(5),
+		})
+	case formRenameDatabase:
+		d, _ := m.selectedDatabase()
+		return db.RenameDatabaseDDL(d.Name, m.formValue(0))
+	case formDropDatabase:
+		d, _ := m.selectedDatabase()
+		if m.formValue(1) != d.Name {
+			return "", fmt.Errorf("type %q to confirm", d.Name)
+		}
+		return db.DropDatabaseDDL(d.Name, m.formBool(0)), nil
+	case formAlterSystem:
+		s, _ := m.selectedSetting()
+		return db.AlterSystemSetDDL(s.Name, m.formValue(0)), nil
+	case formCreateExtension:
+		e, _ := m.selectedExtension()
+		return db.CreateExtensionDDL(e.Name, m.formValue(0), m.formValue(1), m.formBool(2))
+	case formUpdateExtension:
+		e, _ := m.selectedExtension()
+		return db.UpdateExtensionDDL(e.Name, m.formValue(0)), nil
+	case formDropExtension:
+		e, _ := m.selectedExtension()
+		return db.DropExtensionDDL(e.Name, m.formBool(0)), nil
+	}
+	return "", fmt.Errorf("unknown form action %d", m.formAction)
+}
+
+// splitList splits a comma separated form value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (m *Model) formView() string {
+	var b strings.Builder
+	for i, input := range m.formInputs {
+		view := input.View()
+		if len(m.formOptions[i]) > 0 {
+			view = input.Prompt + "‹" + input.Value() + "›"
+		}
+		if i == m.formIndex {
+			b.WriteString(selectedStyle.Render(view))
+		} else {
+			b.WriteString(view)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// confirmDDL shows a statement and runs it only once the user accepts it.
+// The state moves to returnState either way; it receives ddlExecutedMsg on
+// success.
+func (m *Model) confirmDDL(ddl string, returnState State) {
+	m.pendingDDL = ddl
+	m.confirmReturnState = returnState
+	m.err = nil
+	m.state = StateConfirmDDL
+}
+
+func (m *Model) updateConfirmDDL(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch keyMsg.String() {
+	case "y":
+		m.state = m.confirmReturnState
+		// Databases are managed over the maintenance connection, a
+		// connection can't drop or rename the database it is using
+		if m.confirmReturnState == StateSelectDatabase {
+			return execDDL(m.conn, m.pendingDDL)
+		}
+		return execDDL(m.dbConn, m.pendingDDL)
+	case "n", "esc":
+		m.pendingTableRename = ""
+		m.state = m.confirmReturnState
+		// The activity monitor stopped refreshing while the prompt was shown
+		if m.state == StateActivity {
+			return m.openActivity()
+		}
+	}
+	return nil
+}
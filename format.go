@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgtype"
+)
+
+// displayLocation is the time zone timestamptz values are shown in. It is set
+// from the -timezone flag.
+var displayLocation = time.Local
+
+var nullStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Italic(true)
+
+// nullCell is how NULL is stored in grid cells. The trailing zero-width space
+// keeps it apart from a text value that happens to read "NULL", so
+// styleNullCells can highlight only real NULLs after the table is rendered.
+const nullCell = "NULL\u200b"
+
+func styleNullCells(view string) string {
+	return strings.ReplaceAll(view, nullCell, nullStyle.Render("NULL"))
+}
+
+// formatCell renders a value for a single-line grid cell.
+func formatCell(dataType string, value interface{}) string {
+	if value == nil {
+		return nullCell
+	}
+	// Newlines and tabs would break the table layout
+	return strings.NewReplacer("\n", "↵", "\r", "", "\t", " ").Replace(formatValue(dataType, value))
+}
+
+// formatValue renders a value returned by pgx the way Postgres would print
+// it in text format. dataType is the column's format_type() name and is used
+// where the Go value alone is ambiguous, e.g. time.Time for date, timestamp
+// and timestamptz. It may be empty.
+func formatValue(dataType string, value interface{}) string {
+	dataType = baseType(dataType)
+	if value != nil && (dataType == "json" || dataType == "jsonb") {
+		return formatJSONCompact(value)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case int16, int32, int64, int:
+		if dataType == "time without time zone" {
+			return formatTimeOfDay(reflect.ValueOf(v).Int())
+		}
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case [16]byte:
+		return formatUUID(v)
+	case time.Time:
+		return formatTime(dataType, v)
+	case *net.IPNet:
+		ones, bits := v.Mask.Size()
+		if dataType != "cidr" && ones == bits {
+			return v.IP.String()
+		}
+		return v.String()
+	case net.HardwareAddr:
+		return v.String()
+	case pgtype.InfinityModifier:
+		return v.String()
+	case pgtype.Numeric:
+		return formatNumeric(v)
+	case pgtype.Interval:
+		return formatInterval(v)
+	case map[string]interface{}, []interface{}:
+		return formatJSONCompact(v)
+	}
+
+	if s, ok := formatComposite(dataType, value); ok {
+		return s
+	}
+
+	if enc, ok := value.(pgtype.TextEncoder); ok {
+		buf, err := enc.EncodeText(nil, nil)
+		if err == nil {
+			return string(buf)
+		}
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// baseType strips type modifiers, e.g. "timestamp(3) with time zone" becomes
+// "timestamp with time zone" and "numeric(10,2)" becomes "numeric".
+func baseType(dataType string) string {
+	for {
+		start := strings.Index(dataType, "(")
+		if start == -1 {
+			return dataType
+		}
+		end := strings.Index(dataType[start:], ")")
+		if end == -1 {
+			return dataType
+		}
+		dataType = dataType[:start] + dataType[start+end+1:]
+	}
+}
+
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func formatTime(dataType string, t time.Time) string {
+	switch dataType {
+	case "date":
+		return t.Format("2006-01-02")
+	case "timestamp without time zone":
+		return t.Format("2006-01-02 15:04:05.999999")
+	}
+
+	t = t.In(displayLocation)
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	zone := fmt.Sprintf("%c%02d", sign, offset/3600)
+	if minutes := offset % 3600 / 60; minutes != 0 {
+		zone += fmt.Sprintf(":%02d", minutes)
+	}
+	return t.Format("2006-01-02 15:04:05.999999") + zone
+}
+
+func formatTimeOfDay(microseconds int64) string {
+	t := time.Unix(0, microseconds*int64(time.Microsecond)).UTC()
+	return t.Format("15:04:05.999999")
+}
+
+func formatNumeric(n pgtype.Numeric) string {
+	switch {
+	case n.NaN:
+		return "NaN"
+	case n.InfinityModifier == pgtype.Infinity:
+		return "Infinity"
+	case n.InfinityModifier == pgtype.NegativeInfinity:
+		return "-Infinity"
+	case n.Int == nil:
+		return "0"
+	}
+
+	digits := new(big.Int).Abs(n.Int).String()
+	sign := ""
+	if n.Int.Sign() < 0 {
+		sign = "-"
+	}
+
+	if n.Exp >= 0 {
+		return sign + digits + strings.Repeat("0", int(n.Exp))
+	}
+
+	scale := int(-n.Exp)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// formatInterval follows the interval output of the postgres style: only
+// 1 is singular, and a field after a negative one says + when positive.
+func formatInterval(iv pgtype.Interval) string {
+	var parts []string
+	negative := false
+	add := func(n int64, unit string) {
+		if n == 0 {
+			return
+		}
+		part := fmt.Sprintf("%d %s", n, unit)
+		if n != 1 {
+			part += "s"
+		}
+		if negative && n > 0 {
+			part = "+" + part
+		}
+		parts = append(parts, part)
+		negative = n < 0
+	}
+
+	add(int64(iv.Months/12), "year")
+	add(int64(iv.Months%12), "mon")
+	add(int64(iv.Days), "day")
+
+	if iv.Microseconds != 0 || len(parts) == 0 {
+		us := iv.Microseconds
+		sign := ""
+		if us < 0 {
+			sign = "-"
+			us = -us
+		} else if negative {
+			sign = "+"
+		}
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, us/3600000000, us/60000000%60, us/1000000%60)
+		if frac := us % 1000000; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func formatJSONCompact(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// rangeElementTypes maps the built-in range types to their element types.
+var rangeElementTypes = map[string]string{
+	"int4range": "integer",
+	"int8range": "bigint",
+	"numrange":  "numeric",
+	"daterange": "date",
+	"tsrange":   "timestamp without time zone",
+	"tstzrange": "timestamp with time zone",
+}
+
+// formatComposite handles pgtype's array and range structs, formatting their
+// elements with formatValue so nested values match the scalar output.
+func formatComposite(dataType string, value interface{}) (string, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	if elements := v.FieldByName("Elements"); elements.IsValid() && elements.Kind() == reflect.Slice {
+		var dims []pgtype.ArrayDimension
+		if d := v.FieldByName("Dimensions"); d.IsValid() {
+			dims, _ = d.Interface().([]pgtype.ArrayDimension)
+		}
+		elemType := strings.TrimSuffix(dataType, "[]")
+		return formatArray(elemType, elements, dims), true
+	}
+
+	lower, upper := v.FieldByName("LowerType"), v.FieldByName("UpperType")
+	if !lower.IsValid() || !upper.IsValid() {
+		return "", false
+	}
+	lowerType, ok := lower.Interface().(pgtype.BoundType)
+	if !ok {
+		return "", false
+	}
+	upperType, _ := upper.Interface().(pgtype.BoundType)
+	if lowerType == pgtype.Empty {
+		return "empty", true
+	}
+
+	elemType := rangeElementTypes[dataType]
+	var b strings.Builder
+	if lowerType == pgtype.Inclusive {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if lowerType != pgtype.Unbounded {
+		b.WriteString(quoteRangeBound(formatValue(elemType, getValue(v.FieldByName("Lower")))))
+	}
+	b.WriteByte(',')
+	if upperType != pgtype.Unbounded {
+		b.WriteString(quoteRangeBound(formatValue(elemType, getValue(v.FieldByName("Upper")))))
+	}
+	if upperType == pgtype.Inclusive {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String(), true
+}
+
+func getValue(v reflect.Value) interface{} {
+	if getter, ok := v.Interface().(interface{ Get() interface{} }); ok {
+		return getter.Get()
+	}
+	return v.Interface()
+}
+
+func formatArray(elemType string, elements reflect.Value, dims []pgtype.ArrayDimension) string {
+	if elements.Len() == 0 {
+		return "{}"
+	}
+	if len(dims) == 0 {
+		dims = []pgtype.ArrayDimension{{Length: int32(elements.Len())}}
+	}
+
+	var b strings.Builder
+	index := 0
+	var writeDim func(d int)
+	writeDim = func(d int) {
+		b.WriteByte('{')
+		for i := 0; i < int(dims[d].Length); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if d < len(dims)-1 {
+				writeDim(d + 1)
+				continue
+			}
+			elem := getValue(elements.Index(index))
+			index++
+			if elem == nil {
+				b.WriteString("NULL")
+			} else {
+				b.WriteString(quoteArrayElement(formatValue(elemType, elem)))
+			}
+		}
+		b.WriteByte('}')
+	}
+	writeDim(0)
+
+	return b.String()
+}
+
+func quoteArrayElement(s string) string {
+	if s != "" && !strings.EqualFold(s, "NULL") && !strings.ContainsAny(s, "{}\",\\ \t\n") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func quoteRangeBound(s string) string {
+	if s != "" && !strings.ContainsAny(s, "()[],\"\\ \t\n") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
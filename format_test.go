@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+func TestFormatNumeric(t *testing.T) {
+	tests := []struct {
+		n    pgtype.Numeric
+		want string
+	}{
+		{pgtype.Numeric{Int: big.NewInt(12345), Exp: -2}, "123.45"},
+		{pgtype.Numeric{Int: big.NewInt(-12345), Exp: -2}, "-123.45"},
+		{pgtype.Numeric{Int: big.NewInt(5), Exp: -3}, "0.005"},
+		{pgtype.Numeric{Int: big.NewInt(-5), Exp: -3}, "-0.005"},
+		{pgtype.Numeric{Int: big.NewInt(12), Exp: 2}, "1200"},
+		{pgtype.Numeric{Int: big.NewInt(0), Exp: -2}, "0.00"},
+		{pgtype.Numeric{NaN: true}, "NaN"},
+		{pgtype.Numeric{InfinityModifier: pgtype.NegativeInfinity}, "-Infinity"},
+	}
+
+	for _, tt := range tests {
+		if got := formatNumeric(tt.n); got != tt.want {
+			t.Errorf("formatNumeric(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	const hour = 3600000000
+	tests := []struct {
+		name string
+		iv   pgtype.Interval
+		want string
+	}{
+		{"zero", pgtype.Interval{}, "00:00:00"},
+		{"singular", pgtype.Interval{Months: 13, Days: 1, Microseconds: hour}, "1 year 1 mon 1 day 01:00:00"},
+		{"negative is plural", pgtype.Interval{Months: -12, Days: -1}, "-1 years -1 days"},
+		{"mixed signs", pgtype.Interval{Months: -14, Days: 3, Microseconds: -hour - 500000}, "-1 years -2 mons +3 days -01:00:00.5"},
+		{"positive time after negative days", pgtype.Interval{Days: -1, Microseconds: 2 * hour}, "-1 days +02:00:00"},
+		{"hours past a day", pgtype.Interval{Microseconds: 25*hour + 61000000}, "25:01:01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatInterval(tt.iv); got != tt.want {
+				t.Errorf("formatInterval() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	saved := displayLocation
+	defer func() { displayLocation = saved }()
+	displayLocation = time.FixedZone("IST", 5*3600+30*60)
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	tests := []struct {
+		dataType string
+		want     string
+	}{
+		{"date", "2024-01-02"},
+		{"timestamp without time zone", "2024-01-02 03:04:05.5"},
+		{"timestamp with time zone", "2024-01-02 08:34:05.5+05:30"},
+	}
+
+	for _, tt := range tests {
+		if got := formatTime(tt.dataType, ts); got != tt.want {
+			t.Errorf("formatTime(%q) = %q, want %q", tt.dataType, got, tt.want)
+		}
+	}
+
+	displayLocation = time.FixedZone("", -(3*3600 + 30*60))
+	if got, want := formatTime("timestamp with time zone", ts), "2024-01-01 23:34:05.5-03:30"; got != want {
+		t.Errorf("formatTime() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatComposite(t *testing.T) {
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Status: pgtype.Present} }
+	null := pgtype.Text{Status: pgtype.Null}
+	int4 := func(n int32) pgtype.Int4 { return pgtype.Int4{Int: n, Status: pgtype.Present} }
+
+	tests := []struct {
+		name     string
+		dataType string
+		value    interface{}
+		want     string
+	}{
+		{
+			name:     "empty array",
+			dataType: "text[]",
+			value:    pgtype.TextArray{Status: pgtype.Present},
+			want:     "{}",
+		},
+		{
+			name:     "two dimensions with NULL and quoted elements",
+			dataType: "text[]",
+			value: pgtype.TextArray{
+				Elements:   []pgtype.Text{text("a"), null, text("b c"), text(`say "hi"`)},
+				Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}, {Length: 2, LowerBound: 1}},
+				Status:     pgtype.Present,
+			},
+			want: `{{a,NULL},{"b c","say \"hi\""}}`,
+		},
+		{
+			name:     "elements that would read as NULL or empty",
+			dataType: "text[]",
+			value: pgtype.TextArray{
+				Elements:   []pgtype.Text{text("NULL"), text(""), text(`a\b`), text("{x}")},
+				Dimensions: []pgtype.ArrayDimension{{Length: 4, LowerBound: 1}},
+				Status:     pgtype.Present,
+			},
+			want: `{"NULL","","a\\b","{x}"}`,
+		},
+		{
+			name:     "empty range",
+			dataType: "int4range",
+			value:    pgtype.Int4range{LowerType: pgtype.Empty, UpperType: pgtype.Empty, Status: pgtype.Present},
+			want:     "empty",
+		},
+		{
+			name:     "bounded range",
+			dataType: "int4range",
+			value: pgtype.Int4range{Lower: int4(1), Upper: int4(5),
+				LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Status: pgtype.Present},
+			want: "[1,5)",
+		},
+		{
+			name:     "unbounded below",
+			dataType: "int4range",
+			value: pgtype.Int4range{Upper: int4(5),
+				LowerType: pgtype.Unbounded, UpperType: pgtype.Inclusive, Status: pgtype.Present},
+			want: "(,5]",
+		},
+		{
+			name:     "unbounded above",
+			dataType: "int4range",
+			value: pgtype.Int4range{Lower: int4(-3),
+				LowerType: pgtype.Exclusive, UpperType: pgtype.Unbounded, Status: pgtype.Present},
+			want: "(-3,)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := formatComposite(tt.dataType, tt.value)
+			if !ok || got != tt.want {
+				t.Errorf("formatComposite() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteRangeBound(t *testing.T) {
+	tests := []struct{ s, want string }{
+		{"5", "5"},
+		{"", `""`},
+		{"2024-01-02 03:04:05", `"2024-01-02 03:04:05"`},
+		{`a,b`, `"a,b"`},
+		{`a"b\c`, `"a\"b\\c"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteRangeBound(tt.s); got != tt.want {
+			t.Errorf("quoteRangeBound(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type functionsMsg struct{ functions []db.Function }
+type functionDefMsg struct{ def string }
+type triggersMsg struct{ triggers []db.Trigger }
+
+func fetchFunctions(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		functions, err := db.GetFunctions(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return functionsMsg{functions: functions}
+	}
+}
+
+func fetchFunctionDef(conn *pgxpool.Pool, oid uint32) tea.Cmd {
+	return func() tea.Msg {
+		def, err := db.GetFunctionDef(conn, oid)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return functionDefMsg{def: def}
+	}
+}
+
+func fetchTriggers(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		triggers, err := db.GetTriggers(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return triggersMsg{triggers: triggers}
+	}
+}
+
+func (m *Model) openFunctions() tea.Cmd {
+	m.functions = nil
+	m.functionSchema = ""
+	m.initFunctionTable()
+	m.err = nil
+	m.state = StateFunctions
+	return fetchFunctions(m.dbConn)
+}
+
+// openFunctionSource shows the definition of a function, going back to
+// returnState on esc.
+func (m *Model) openFunctionSource(title string, oid uint32, returnState State) tea.Cmd {
+	m.functionTitle = title
+	m.functionOID = oid
+	m.functionReturnState = returnState
+	m.functionSource.SetContent("Loading...")
+	m.functionSource.GotoTop()
+	m.err = nil
+	m.state = StateFunctionSource
+	return fetchFunctionDef(m.dbConn, oid)
+}
+
+// editFunction opens the definition of a function in $EDITOR.
+func (m *Model) editFunction(title string, oid uint32, returnState State) tea.Cmd {
+	conn := m.dbConn
+	return m.editDefinitionOf(title, func() (string, error) {
+		return db.GetFunctionDef(conn, oid)
+	}, returnState)
+}
+
+// functionSchemas returns the schemas that have functions, in order.
+func (m *Model) functionSchemas() []string {
+	var schemas []string
+	for _, f := range m.functions {
+		if len(schemas) == 0 || schemas[len(schemas)-1] != f.Schema {
+			schemas = append(schemas, f.Schema)
+		}
+	}
+	return schemas
+}
+
+// nextFunctionSchema cycles the schema filter through every schema, then
+// back to showing all of them. A schema that has gone away starts over
+// from showing all.
+func (m *Model) nextFunctionSchema() {
+	schemas := append([]string{""}, m.functionSchemas()...)
+	m.functionSchema = schemas[(indexOf(schemas, m.functionSchema)+1)%len(schemas)]
+}
+
+func (m *Model) initFunctionTable() {
+	columns := []table.Column{
+		{Title: "Schema", Width: 14},
+		{Title: "Name", Width: 24},
+		{Title: "Arguments", Width: max(m.windowSize.Width-4-112-16, 20)},
+		{Title: "Returns", Width: 20},
+		{Title: "Kind", Width: 9},
+		{Title: "Language", Width: 8},
+		{Title: "Volatility", Width: 10},
+		{Title: "Security", Width: 8},
+	}
+
+	m.visibleFunctions = nil
+	for _, f := range m.functions {
+		if m.functionSchema == "" || f.Schema == m.functionSchema {
+			m.visibleFunctions = append(m.visibleFunctions, f)
+		}
+	}
+	rows := make([]table.Row, len(m.visibleFunctions))
+	for i, f := range m.visibleFunctions {
+		security := "invoker"
+		if f.SecurityDefiner {
+			security = "definer"
+		}
+		rows[i] = table.Row{f.Schema, f.Name, f.Arguments, f.Result, f.Kind, f.Language, f.Volatility, security}
+	}
+
+	cursor := m.functionTable.Cursor()
+	m.functionTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-12),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.functionTable.SetStyles(tableStyle)
+	m.functionTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateFunctions(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.functionTable, cmd = m.functionTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case functionsMsg:
+		m.functions = msg.functions
+		m.initFunctionTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchFunctions(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchFunctions(m.dbConn))
+		case "s":
+			m.nextFunctionSchema()
+			m.initFunctionTable()
+		case "enter", "e":
+			if len(m.visibleFunctions) == 0 {
+				break
+			}
+			f := m.visibleFunctions[m.functionTable.Cursor()]
+			if f.Kind == "aggregate" {
+				m.err = fmt.Errorf("aggregates have no source to show")
+				break
+			}
+			title := fmt.Sprintf("%s %s.%s(%s)", f.Kind, f.Schema, f.Name, f.Arguments)
+			if msg.String() == "e" {
+				cmds = append(cmds, m.editFunction(title, f.OID, StateFunctions))
+			} else {
+				cmds = append(cmds, m.openFunctionSource(title, f.OID, StateFunctions))
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) updateFunctionSource(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.functionSource, cmd = m.functionSource.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case functionDefMsg:
+		m.functionSource.SetContent(highlightSQL(msg.def))
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchFunctionDef(m.dbConn, m.functionOID))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = m.functionReturnState
+		case "e":
+			cmds = append(cmds, m.editFunction(m.functionTitle, m.functionOID, StateFunctionSource))
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) openTriggers(tableName string) tea.Cmd {
+	m.selectedTable = tableName
+	m.triggers = nil
+	m.initTriggerTable()
+	m.err = nil
+	m.state = StateTriggers
+	return fetchTriggers(m.dbConn, tableName)
+}
+
+func (m *Model) initTriggerTable() {
+	columns := []table.Column{
+		{Title: "Trigger", Width: 24},
+		{Title: "Timing", Width: 10},
+		{Title: "Events", Width: 30},
+		{Title: "Level", Width: 9},
+		{Title: "State", Width: 8},
+		{Title: "Function", Width: max(m.windowSize.Width-4-81-12, 20)},
+	}
+
+	rows := make([]table.Row, len(m.triggers))
+	for i, t := range m.triggers {
+		rows[i] = table.Row{t.Name, t.Timing, t.Events, t.Level, t.Enabled, t.Function}
+	}
+
+	cursor := m.triggerTable.Cursor()
+	m.triggerTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.triggerTable.SetStyles(tableStyle)
+	m.triggerTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) selectedTrigger() (db.Trigger, bool) {
+	if len(m.triggers) == 0 {
+		return db.Trigger{}, false
+	}
+	return m.triggers[m.triggerTable.Cursor()], true
+}
+
+func (m *Model) updateTriggers(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.triggerTable, cmd = m.triggerTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case triggersMsg:
+		m.triggers = msg.triggers
+		m.initTriggerTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchTriggers(m.dbConn, m.selectedTable))
+	case tea.KeyMsg:
+		t, ok := m.selectedTrigger()
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "enter":
+			if ok {
+				enable := t.Enabled == "disabled"
+				m.confirmDDL(db.SetTriggerEnabledDDL(m.selectedTable, t.Name, enable), StateTriggers)
+			}
+		case "o":
+			if ok {
+				cmds = append(cmds, m.openFunctionSource("function "+t.Function, t.FunctionOID, StateTriggers))
+			}
+		case "e":
+			if ok {
+				cmds = append(cmds, m.editFunction("function "+t.Function, t.FunctionOID, StateTriggers))
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) functionsView() string {
+	schema := m.functionSchema
+	if schema == "" {
+		schema = "all schemas"
+	}
+	return fmt.Sprintf("%d functions and procedures in %s\n\n%s", len(m.visibleFunctions), schema, m.functionTable.View())
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	minColumnWidth   = 3
+	maxColumnWidth   = 40
+	columnSampleRows = 200
+	// Cells are padded by one space on either side by the table styles
+	columnPadding = 2
+)
+
+// initColumnLayout sizes every column to fit its header and a sample of its
+// values, and pins the primary key so it stays visible while scrolling.
+func (m *Model) initColumnLayout() {
+	m.columnWidths = make([]int, len(m.dataColumns))
+	m.pinnedColumns = make([]bool, len(m.dataColumns))
+	m.gridFocus = 0
+	m.gridOffset = 0
+
+	for i, col := range m.dataColumns {
+		width := lipgloss.Width(col.Name)
+		for r := 0; r < len(m.gridCells) && r < columnSampleRows; r++ {
+			width = max(width, lipgloss.Width(m.gridCells[r][i]))
+		}
+		m.columnWidths[i] = min(max(width, minColumnWidth), maxColumnWidth)
+		m.pinnedColumns[i] = col.PrimaryKey
+	}
+}
+
+// visibleColumns returns the indexes of the columns that fit on screen:
+// pinned columns (marked with *) first, then unpinned columns starting at
+// the scroll offset.
+func (m *Model) visibleColumns() []int {
+	available := m.dataTable.Width()
+	used := 0
+
+	var visible []int
+	for i, pinned := range m.pinnedColumns {
+		if pinned {
+			visible = append(visible, i)
+			used += m.columnWidths[i] + columnPadding
+		}
+	}
+
+	scrolled := 0
+	for i := m.gridOffset; i < len(m.dataColumns); i++ {
+		if m.pinnedColumns[i] {
+			continue
+		}
+		width := m.columnWidths[i] + columnPadding
+		// Always show at least one scrolled column, even if it gets cut off
+		if scrolled > 0 && used+width > available {
+			break
+		}
+		visible = append(visible, i)
+		used += width
+		scrolled++
+	}
+
+	return visible
+}
+
+// refreshGrid rebuilds the table's columns and rows from the current layout.
+func (m *Model) refreshGrid() {
+	if len(m.columnWidths) != len(m.dataColumns) {
+		return
+	}
+
+	visible := m.visibleColumns()
+
+	columns := make([]table.Column, len(visible))
+	for i, c := range visible {
+		title := m.dataColumns[c].Name
+		if m.pinnedColumns[c] {
+			title = "*" + title
+		}
+		if _, ok := m.foreignKeyForColumn(m.dataColumns[c].Name); ok {
+			title += "→"
+		}
+		if c == m.gridFocus {
+			title = "›" + title
+		}
+		columns[i] = table.Column{Title: title, Width: m.columnWidths[c]}
+	}
+
+	rows := make([]table.Row, len(m.gridCells))
+	for r, cells := range m.gridCells {
+		row := make(table.Row, len(visible))
+		for i, c := range visible {
+			row[i] = cells[c]
+		}
+		rows[r] = row
+	}
+
+	// Clear the rows first: SetColumns re-renders the existing rows, which
+	// may have fewer cells than the new column set.
+	m.dataTable.SetRows(nil)
+	m.dataTable.SetColumns(columns)
+	m.dataTable.SetRows(rows)
+}
+
+// moveColumnFocus moves the focused column and scrolls it into view.
+func (m *Model) moveColumnFocus(delta int) {
+	if len(m.dataColumns) == 0 {
+		return
+	}
+	m.gridFocus = min(max(m.gridFocus+delta, 0), len(m.dataColumns)-1)
+
+	if !m.pinnedColumns[m.gridFocus] {
+		if m.gridFocus < m.gridOffset {
+			m.gridOffset = m.gridFocus
+		}
+		for !m.isColumnVisible(m.gridFocus) && m.gridOffset < m.gridFocus {
+			m.gridOffset++
+		}
+	}
+
+	m.refreshGrid()
+}
+
+func (m *Model) isColumnVisible(col int) bool {
+	for _, c := range m.visibleColumns() {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) resizeFocusedColumn(delta int) {
+	if len(m.columnWidths) == 0 {
+		return
+	}
+	m.columnWidths[m.gridFocus] = max(m.columnWidths[m.gridFocus]+delta, minColumnWidth)
+	m.refreshGrid()
+}
+
+func (m *Model) togglePinnedColumn() {
+	if len(m.pinnedColumns) == 0 {
+		return
+	}
+	m.pinnedColumns[m.gridFocus] = !m.pinnedColumns[m.gridFocus]
+	m.refreshGrid()
+}
+
+// gridStatus describes which columns are on screen, e.g. "columns 4-9 of 60".
+func (m *Model) gridStatus() string {
+	visible := m.visibleColumns()
+	if len(visible) == len(m.dataColumns) {
+		return ""
+	}
+
+	first, last := -1, -1
+	for _, c := range visible {
+		if m.pinnedColumns[c] {
+			continue
+		}
+		if first == -1 {
+			first = c
+		}
+		last = c
+	}
+	if first == -1 {
+		return ""
+	}
+	return detailTypeStyle.Render(fmt.Sprintf("columns %d-%d of %d", first+1, last+1, len(m.dataColumns)))
+}
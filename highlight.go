@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	sqlKeywordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("75")).Bold(true)
+	sqlStringStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	sqlNumberStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("180"))
+	sqlCommentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("243")).Italic(true)
+	sqlQuoteStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("176"))
+)
+
+// sqlKeywords covers SQL and PL/pgSQL, enough to make function bodies and
+// DDL readable rather than to be complete.
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		ADD ALL ALTER AND ANY ARRAY AS ASC BEGIN BETWEEN BY CALL CASCADE CASE CAST CHECK CLOSE COLLATE
+		COLUMN COMMENT COMMIT CONCURRENTLY CONSTANT CONSTRAINT CONTINUE COST CREATE CROSS CURSOR DECLARE
+		DEFAULT DEFERRABLE DEFINER DELETE DESC DIAGNOSTICS DISTINCT DO DOMAIN DROP EACH ELSE ELSIF END
+		ENUM EXCEPTION EXECUTE EXISTS EXIT EXTENSION FALSE FETCH FOR FOREACH FOREIGN FOUND FROM FULL
+		FUNCTION GET GRANT GROUP HAVING IF ILIKE IMMUTABLE IN INDEX INHERITS INNER INOUT INSERT INTO
+		INVOKER IS JOIN KEY LANGUAGE LEAKPROOF LEFT LIKE LIMIT LOOP MATERIALIZED NEW NEXT NOT NOTICE
+		NULL OF OFFSET OLD ON OPEN OR ORDER OUT OUTER OWNED OWNER PARALLEL PERFORM PRIMARY PROCEDURE
+		QUERY RAISE RECORD REFERENCES RENAME REPLACE RESTRICT RETURN RETURNING RETURNS REVERSE REVOKE
+		RIGHT ROLLBACK ROW ROWS SAFE SCHEMA SECURITY SELECT SEQUENCE SET SETOF SLICE STABLE STRICT
+		TABLE THEN TO TRIGGER TRUE TYPE UNION UNIQUE UNSAFE UPDATE USING VALUES VIEW VOLATILE WHEN
+		WHERE WHILE WINDOW WITH`) {
+		sqlKeywords[k] = true
+	}
+}
+
+var sqlTokenPattern = regexp.MustCompile(`(?s)` +
+	`--[^\n]*` + // line comment
+	`|/\*.*?\*/` + // block comment
+	`|'(?:[^']|'')*'?` + // string literal
+	`|\$[A-Za-z_]*\$` + // dollar quote delimiter
+	`|"(?:[^"]|"")*"?` + // quoted identifier
+	`|[A-Za-z_][A-Za-z0-9_$]*` + // word
+	`|[0-9]+(?:\.[0-9]+)?`) // number
+
+// highlightSQL colors keywords, literals and comments. The bodies of
+// dollar-quoted strings are highlighted as code, since they are almost
+// always function bodies.
+func highlightSQL(sql string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range sqlTokenPattern.FindAllStringIndex(sql, -1) {
+		b.WriteString(sql[last:loc[0]])
+		token := sql[loc[0]:loc[1]]
+		last = loc[1]
+
+		switch c := token[0]; {
+		case strings.HasPrefix(token, "--"), strings.HasPrefix(token, "/*"):
+			b.WriteString(renderLines(sqlCommentStyle, token))
+		case c == '\'':
+			b.WriteString(renderLines(sqlStringStyle, token))
+		case c == '$':
+			b.WriteString(sqlQuoteStyle.Render(token))
+		case c >= '0' && c <= '9':
+			b.WriteString(sqlNumberStyle.Render(token))
+		case c != '"' && sqlKeywords[strings.ToUpper(token)]:
+			b.WriteString(sqlKeywordStyle.Render(token))
+		default:
+			b.WriteString(token)
+		}
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// renderLines styles each line on its own, so a multi-line token isn't
+// padded into a block.
+func renderLines(style lipgloss.Style, s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = style.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type indexesMsg struct{ indexes []db.Index }
+
+func fetchIndexes(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		indexes, err := db.GetIndexes(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return indexesMsg{indexes: indexes}
+	}
+}
+
+func (m *Model) initIndexTable() {
+	columns := []table.Column{
+		{Title: "Index", Width: 28},
+		{Title: "Size", Width: 10},
+		{Title: "Scans", Width: 10},
+		{Title: "Tuples Read", Width: 12},
+		{Title: "Flag", Width: 7},
+		{Title: "Definition", Width: max(m.windowSize.Width-4-67-12, 30)},
+	}
+
+	rows := make([]table.Row, len(m.indexes))
+	for i, idx := range m.indexes {
+		flag := ""
+		switch {
+		case idx.Primary:
+			flag = "primary"
+		case idx.Unused():
+			flag = "unused"
+		}
+		rows[i] = table.Row{
+			idx.Name,
+			idx.Size,
+			fmt.Sprintf("%d", idx.Scans),
+			fmt.Sprintf("%d", idx.TuplesRead),
+			flag,
+			idx.Definition,
+		}
+	}
+
+	cursor := m.indexTable.Cursor()
+	m.indexTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.indexTable.SetStyles(tableStyle)
+	m.indexTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateIndexes(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.indexTable, cmd = m.indexTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case indexesMsg:
+		m.indexes = msg.indexes
+		m.initIndexTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchIndexes(m.dbConn, m.selectedTable))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "c":
+			m.openForm(formCreateIndex, fmt.Sprintf("Create Index on %s", m.selectedTable), StateIndexes,
+				formField{prompt: "Name", placeholder: "optional, generated when empty"},
+				formField{prompt: "Columns", placeholder: "comma separated, e.g. last_name, first_name"},
+				formField{prompt: "Method", options: db.IndexMethods},
+				formField{prompt: "Unique", options: yesNo},
+				formField{prompt: "Where", placeholder: "optional predicate for a partial index"},
+				formField{prompt: "Concurrently", options: yesNo},
+			)
+		case "x":
+			if len(m.indexes) > 0 {
+				idx := m.indexes[m.indexTable.Cursor()]
+				m.confirmDDL(db.DropIndexDDL(idx.Name, false), StateIndexes)
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type blockingChainsMsg struct{ backends []db.LockedBackend }
+
+// lockTreeRow is a line of the blocking tree. The same backend shows up
+// once under every backend it waits for.
+type lockTreeRow struct {
+	prefix  string
+	backend db.LockedBackend
+	root    int
+}
+
+func fetchBlockingChains(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		backends, err := db.GetBlockingChains(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return blockingChainsMsg{backends: backends}
+	}
+}
+
+func (m *Model) openLocks() tea.Cmd {
+	m.lockRows = nil
+	m.initLockTable()
+	m.err = nil
+	m.state = StateLocks
+	return fetchBlockingChains(m.dbConn)
+}
+
+// buildLockTree lays the backends out as trees, each rooted at a backend
+// that holds locks without waiting for any. A blocker that isn't a backend
+// of its own, such as a prepared transaction (PID 0), is a root too, and
+// backends waiting on each other in a cycle are rooted at the first one.
+func buildLockTree(backends []db.LockedBackend) []lockTreeRow {
+	byPID := map[int]db.LockedBackend{}
+	children := map[int][]int{}
+	for _, b := range backends {
+		byPID[b.PID] = b
+		for _, blocker := range b.BlockedBy {
+			children[blocker] = append(children[blocker], b.PID)
+		}
+	}
+
+	var rows []lockTreeRow
+	var walk func(pid, root int, indent string, last bool, path map[int]bool)
+	walk = func(pid, root int, indent string, last bool, path map[int]bool) {
+		branch, next := "├─ ", "│  "
+		if last {
+			branch, next = "└─ ", "   "
+		}
+		if pid == root {
+			branch, next = "", ""
+		}
+		rows = append(rows, lockTreeRow{prefix: indent + branch, backend: byPID[pid], root: root})
+
+		// Guard against waits that form a cycle until the deadlock
+		// detector breaks it
+		path[pid] = true
+		defer delete(path, pid)
+		kids := children[pid]
+		for i, child := range kids {
+			if !path[child] {
+				walk(child, root, indent+next, i == len(kids)-1, path)
+			}
+		}
+	}
+
+	var roots []int
+	for _, b := range backends {
+		if len(b.BlockedBy) == 0 {
+			roots = append(roots, b.PID)
+		}
+	}
+	for _, b := range backends {
+		for _, blocker := range b.BlockedBy {
+			if _, ok := byPID[blocker]; !ok {
+				missing := db.LockedBackend{PID: blocker}
+				if blocker == 0 {
+					missing.State = "prepared transaction"
+				}
+				byPID[blocker] = missing
+				roots = append(roots, blocker)
+			}
+		}
+	}
+	for _, pid := range roots {
+		walk(pid, pid, "", true, map[int]bool{})
+	}
+
+	// Whatever is left waits in a cycle
+	shown := map[int]bool{}
+	for _, r := range rows {
+		shown[r.backend.PID] = true
+	}
+	for _, b := range backends {
+		if !shown[b.PID] {
+			start := len(rows)
+			walk(b.PID, b.PID, "", true, map[int]bool{})
+			for _, r := range rows[start:] {
+				shown[r.backend.PID] = true
+			}
+		}
+	}
+	return rows
+}
+
+// lockSummary puts the lock being waited for first, then the held ones.
+func lockSummary(locks []db.Lock) string {
+	var parts []string
+	for _, l := range locks {
+		verb := "holds"
+		if !l.Granted {
+			verb = "waits for"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s on %s", verb, l.Mode, l.Object))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (m *Model) initLockTable() {
+	columns := []table.Column{
+		{Title: "PID", Width: 20},
+		{Title: "User", Width: 12},
+		{Title: "State", Width: 19},
+		{Title: "Xact", Width: 8},
+		{Title: "Locks", Width: 50},
+		{Title: "Query", Width: max(m.windowSize.Width-4-109-12, 20)},
+	}
+
+	rows := make([]table.Row, len(m.lockRows))
+	for i, r := range m.lockRows {
+		rows[i] = table.Row{
+			fmt.Sprintf("%s%d", r.prefix, r.backend.PID),
+			r.backend.User,
+			r.backend.State,
+			formatDuration(r.backend.Duration),
+			lockSummary(r.backend.Locks),
+			strings.Join(strings.Fields(r.backend.Query), " "),
+		}
+	}
+
+	cursor := m.lockTable.Cursor()
+	m.lockTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.lockTable.SetStyles(tableStyle)
+	m.lockTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateLocks(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.lockTable, cmd = m.lockTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case blockingChainsMsg:
+		m.lockRows = buildLockTree(msg.backends)
+		m.initLockTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchBlockingChains(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchBlockingChains(m.dbConn))
+		case "x":
+			if len(m.lockRows) > 0 {
+				root := m.lockRows[m.lockTable.Cursor()].root
+				if root == 0 {
+					m.err = fmt.Errorf("a prepared transaction has no backend to terminate, use COMMIT PREPARED or ROLLBACK PREPARED")
+					break
+				}
+				m.confirmDDL(db.TerminateBackendSQL(root), StateLocks)
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
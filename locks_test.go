@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"lazysql/db"
+)
+
+func TestBuildLockTree(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []db.LockedBackend
+		want     []string
+	}{
+		{
+			name: "chain",
+			backends: []db.LockedBackend{
+				{PID: 1},
+				{PID: 2, BlockedBy: []int{1}},
+				{PID: 3, BlockedBy: []int{2}},
+				{PID: 4, BlockedBy: []int{1}},
+			},
+			want: []string{"1", "├─ 2", "│  └─ 3", "└─ 4"},
+		},
+		{
+			name: "blocked by a prepared transaction",
+			backends: []db.LockedBackend{
+				{PID: 5, BlockedBy: []int{0}},
+			},
+			want: []string{"0", "└─ 5"},
+		},
+		{
+			name: "cycle",
+			backends: []db.LockedBackend{
+				{PID: 7, BlockedBy: []int{8}},
+				{PID: 8, BlockedBy: []int{7}},
+			},
+			want: []string{"7", "└─ 8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range buildLockTree(tt.backends) {
+				got = append(got, r.prefix+strconv.Itoa(r.backend.PID))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildLockTree() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,24 @@
-package main
-
-import (
-	"context"
-	"fmt"
-	"io"
-	"log"
-	"strings"
-
-	"lazysql/db"
-
-	"github.com/charmbracelet/bubbles/list"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type State int
@@ -31,50 +34,257 @@ const (
 	StateCreateTableSchema
 	StateViewTable
 	StateAddRow
+	StateRecordDetail
+	StateTableStructure
+	StateForm
+	StateConfirmDDL
+	StateIndexes
+	StateConstraints
+	StateForeignKeyTable
+	StateForeignKeyColumns
+	StateReferencedBy
+	StateConfirmTableAction
+	StateRoles
+	StatePrivileges
+	StatePrivilegeObjects
+	StateDefaultPrivileges
+	StateActivity
+	StateLocks
+	StateTableStats
+	StateTopQueries
+	StateTopQueryDetail
+	StateSettings
+	StateExtensions
+	StateFunctions
+	StateFunctionSource
+	StateTriggers
+	StateViews
+	StateEditDiff
+	StateQueryResult
+	StateDDL
+	StateCompareTarget
+	StateSchemaDiff
 	StateError
 )
 
+// Model holds what all tabs share. Everything about one connection lives in
+// a Session, and the active one is embedded so its fields read as the
+// model's own.
 type Model struct {
+	*Session
+	sessions      []*Session
+	nextSessionID int
+
+	spinner spinner.Model
+	// spinnerTicking is set while a tick of the spinner is pending
+	spinnerTicking bool
+	conn           *pgxpool.Pool
+	connErr        error
+	windowSize     tea.WindowSizeMsg
+}
+
+// Session is one tab: a connection and the state of every view on it.
+type Session struct {
+	id            int
 	state         State
-	spinner       spinner.Model
 	userList      list.Model
 	databaseList  list.Model
 	passwordInput textinput.Model
 	tableList     list.Model
-	conn          *pgx.Conn
-	connErr       error
 	selectedUser  string
 	selectedDB    string
+	databases     []db.Database
 	userPassword  string
-	dbConn        *pgx.Conn
+	dbConn        *pgxpool.Pool
 	tables        []string
 	err           error
 
+	// reconnectAttempt counts failed health checks since the connection
+	// was last known to work
+	reconnectAttempt int
+
 	// Fields for table creation
-	tableNameInput   textinput.Model
-	tableSchemaInput textinput.Model
-	tableName        string
-	tableSchema      string
+	tableNameInput  textinput.Model
+	tableName       string
+	designerColumns []designerColumn
+	designerRow     int
+	designerField   int
 
 	// Fields for viewing table contents
 	selectedTable     string
 	tableData         []map[string]interface{}
+	dataColumns       []db.Column
+	foreignKeys       []db.ForeignKey
+	rowFilter         db.RowFilter
 	dataTable         table.Model
+	gridCells         [][]string
 	tableColumns      []string
 	addRowInputs      []textinput.Model
 	currentInputIndex int
 
-	windowSize tea.WindowSizeMsg
+	// Fields for the grid column layout
+	gridTable     string
+	columnWidths  []int
+	pinnedColumns []bool
+	gridFocus     int
+	gridOffset    int
+
+	// Fields for following foreign keys between tables
+	navStack        []gridLocation
+	restoreLocation *gridLocation
+	referencingKeys []db.ForeignKey
+	referencedBy    list.Model
+
+	// Fields for drop, truncate and rename confirmations
+	tableAction        tableAction
+	tableActionOptions []tableActionOption
+	tableActionFocus   int
+	tableActionNewName textinput.Model
+	tableActionConfirm textinput.Model
+	dependentObjects   []string
+
+	// Fields for the record detail pane
+	recordView viewport.Model
+
+	// Fields for the table structure view
+	structureTable     table.Model
+	structureColumns   []db.Column
+	pendingTableRename string
+
+	// Fields for the indexes panel
+	indexTable table.Model
+	indexes    []db.Index
+
+	// Fields for the constraints panel
+	constraintTable   table.Model
+	constraints       []db.Constraint
+	constraintColumns []db.Column
+	fkTableList       list.Model
+	fkColumnList      list.Model
+	fkRefTable        string
+	fkRefColumns      []db.Column
+	fkSelected        []bool
+
+	// Fields for the roles view
+	roleTable table.Model
+	roles     []db.Role
+
+	// Fields for the privileges matrix
+	privilegeObject       db.PrivilegeObject
+	privileges            []db.RolePrivileges
+	privilegeTable        table.Model
+	privilegeFocus        int
+	privilegeObjects      []db.PrivilegeObject
+	privilegeObjectList   list.Model
+	defaultPrivileges     []db.DefaultPrivilege
+	defaultPrivilegeTable table.Model
+
+	// Fields for the activity monitor
+	activityTable      table.Model
+	backends           []db.Backend
+	visibleActivity    []db.Backend
+	activityGeneration int
+	hideIdle           bool
+	activityGrouping   activityGrouping
+
+	// Fields for the lock inspector
+	lockTable table.Model
+	lockRows  []lockTreeRow
+
+	// Fields for the table statistics dashboard
+	tableStats         *db.TableStats
+	maintenanceLog     []string
+	maintenanceLogView viewport.Model
+	maintenanceRunning bool
+	// maintenanceCtx is cancelled when the tab closes, which stops a run
+	maintenanceCtx    context.Context
+	cancelMaintenance context.CancelFunc
+
+	// Fields for the top queries view
+	statementStats          []db.StatementStat
+	statStatementsSchema    string
+	statStatementsMissing   bool
+	statStatementsNotLoaded bool
+	statementSort           db.StatementSort
+	statementTable          table.Model
+	selectedStatement       db.StatementStat
+	statementPlan           []string
+	statementView           viewport.Model
+
+	// Fields for the server settings browser
+	settingsList      list.Model
+	settingsSuperuser bool
+
+	// Fields for the extensions manager
+	extensions     []db.Extension
+	extensionTable table.Model
+
+	// Fields for the functions and triggers browser
+	functions           []db.Function
+	visibleFunctions    []db.Function
+	functionSchema      string
+	functionOID         uint32
+	functionTable       table.Model
+	functionTitle       string
+	functionSource      viewport.Model
+	functionReturnState State
+	triggers            []db.Trigger
+	triggerTable        table.Model
+
+	// Fields for editing definitions and queries in $EDITOR
+	viewList        list.Model
+	editTarget      editTarget
+	editTitle       string
+	editOriginal    string
+	editText        string
+	editFile        string
+	editReturnState State
+	editDiffView    viewport.Model
+	lastQuery       string
+	queryResult     db.QueryResult
+	queryRunning    bool
+	queryTable      table.Model
+
+	// Fields for the DDL view
+	ddlTitle       string
+	ddlView        viewport.Model
+	ddlReturnState State
+
+	// Fields for the schema diff
+	compareTargets    []compareTarget
+	compareList       list.Model
+	schemaDiffFrom    string
+	schemaDiffTo      string
+	schemaSnapshots   [2][]db.SchemaObject
+	schemaChanges     []db.SchemaChange
+	schemaDiffRunning bool
+	schemaDiffTable   table.Model
+	schemaFromView    viewport.Model
+	schemaToView      viewport.Model
+
+	// Fields for forms and DDL confirmation
+	formInputs         []textinput.Model
+	formOptions        [][]string
+	formIndex          int
+	formAction         formAction
+	formTitle          string
+	formReturnState    State
+	pendingDDL         string
+	confirmReturnState State
 }
 
 type postgresFoundMsg struct{}
 type postgresNotFoundMsg struct{}
 type usersMsg struct{ users []string }
-type databasesMsg struct{ databases []string }
-type connectedMsg struct{ conn *pgx.Conn }
+type databasesMsg struct{ databases []db.Database }
+type connectedMsg struct{ conn *pgxpool.Pool }
 type tablesMsg struct{ tables []string }
 type tableCreatedMsg struct{}
-type tableDataMsg struct{ data []map[string]interface{} }
+type tableDataMsg struct {
+	data        []map[string]interface{}
+	columns     []db.Column
+	foreignKeys []db.ForeignKey
+}
 type tableColumnsMsg struct{ columns []string }
 type rowInsertedMsg struct{}
 type errMsg struct{ err error }
@@ -128,14 +338,16 @@ func (d customDelegate) Render(w io.Writer, m list.Model, index int, item list.I
 		renderStyle = selectedTitle
 	}
 
-	fmt.Fprintf(w, "%s %s\n", cursor, renderStyle.Render(title))
-}
+	line := renderStyle.Render(title)
+	if desc := i.Description(); desc != "" {
+		line += "  " + detailTypeStyle.Render(desc)
+	}
 
-func initializeModel() *Model {
-	s := spinner.New()
-	s.Style = spinnerStyle
+	fmt.Fprintf(w, "%s %s\n", cursor, line)
+}
 
-	// Custom list styles to remove unwanted lines
+// Custom list styles to remove unwanted lines
+func newListStyles() list.Styles {
 	listStyles := list.DefaultStyles()
 	listStyles.Title = lipgloss.NewStyle().Bold(true).PaddingLeft(2)
 	listStyles.PaginationStyle = lipgloss.NewStyle()
@@ -143,45 +355,28 @@ func initializeModel() *Model {
 	listStyles.FilterCursor = lipgloss.NewStyle()
 	listStyles.FilterPrompt = lipgloss.NewStyle()
 	listStyles.NoItems = lipgloss.NewStyle().PaddingLeft(2)
+	return listStyles
+}
 
-	delegate := customDelegate{}
-
-	userList := list.New([]list.Item{}, delegate, 0, 0)
-	userList.Title = "Select User"
-	userList.SetShowStatusBar(false)
-	userList.SetFilteringEnabled(false)
-	userList.Styles = listStyles
-
-	databaseList := list.New([]list.Item{}, delegate, 0, 0)
-	databaseList.Title = "Select Database"
-	databaseList.SetShowStatusBar(false)
-	databaseList.SetFilteringEnabled(false)
-	databaseList.Styles = listStyles
-
-	passwordInput := textinput.New()
-	passwordInput.Placeholder = "Enter password"
-	passwordInput.EchoMode = textinput.EchoPassword
-	// Remove unnecessary focus
-	// passwordInput.Focus()
-
-	tableList := list.New([]list.Item{}, delegate, 0, 0)
-	tableList.Title = "Tables"
-	tableList.SetShowStatusBar(false)
-	tableList.SetFilteringEnabled(false)
-	tableList.Styles = listStyles
-
-	dataTable := table.New()
-	dataTable.SetStyles(tableStyle)
-
-	return &Model{
-		state:         StateLoading,
-		spinner:       s,
-		userList:      userList,
-		databaseList:  databaseList,
-		passwordInput: passwordInput,
-		tableList:     tableList,
-		dataTable:     dataTable,
-	}
+// newPickerList creates a list sized to the window for picking one of items.
+func (m *Model) newPickerList(title string, items []string) list.Model {
+	l := list.New(convertToListItems(items), customDelegate{}, m.windowSize.Width-4, m.windowSize.Height-10)
+	l.Title = title
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	// Esc goes back from a picker instead of quitting
+	l.DisableQuitKeybindings()
+	l.Styles = newListStyles()
+	return l
+}
+
+func initializeModel() *Model {
+	s := spinner.New()
+	s.Style = spinnerStyle
+
+	m := &Model{spinner: s}
+	m.openSession(StateLoading)
+	return m
 }
 
 func checkDbInstalled() tea.Cmd {
@@ -193,7 +388,7 @@ func checkDbInstalled() tea.Cmd {
 	}
 }
 
-func fetchUsers(conn *pgx.Conn) tea.Cmd {
+func fetchUsers(conn *pgxpool.Pool) tea.Cmd {
 	return func() tea.Msg {
 		users, err := db.GetUsers(conn)
 		if err != nil {
@@ -203,7 +398,7 @@ func fetchUsers(conn *pgx.Conn) tea.Cmd {
 	}
 }
 
-func fetchDatabases(conn *pgx.Conn) tea.Cmd {
+func fetchDatabases(conn *pgxpool.Pool) tea.Cmd {
 	return func() tea.Msg {
 		databases, err := db.GetDatabases(conn)
 		if err != nil {
@@ -215,7 +410,7 @@ func fetchDatabases(conn *pgx.Conn) tea.Cmd {
 
 func connectAsUser(username, password, database string) tea.Cmd {
 	return func() tea.Msg {
-		conn, err := db.ConnectAsUser(username, password, database)
+		conn, err := db.ConnectAsUser(username, password, database, sessionSettings)
 		if err != nil {
 			return errMsg{err: err}
 		}
@@ -223,7 +418,7 @@ func connectAsUser(username, password, database string) tea.Cmd {
 	}
 }
 
-func fetchTables(conn *pgx.Conn) tea.Cmd {
+func fetchTables(conn *pgxpool.Pool) tea.Cmd {
 	return func() tea.Msg {
 		tables, err := db.GetTables(conn)
 		if err != nil {
@@ -233,9 +428,9 @@ func fetchTables(conn *pgx.Conn) tea.Cmd {
 	}
 }
 
-func createTable(conn *pgx.Conn, tableName, schema string) tea.Cmd {
+func createTable(conn *pgxpool.Pool, tableName string, columns []db.ColumnDef) tea.Cmd {
 	return func() tea.Msg {
-		err := db.CreateTable(conn, tableName, schema)
+		err := db.CreateTable(conn, tableName, columns)
 		if err != nil {
 			return errMsg{err: err}
 		}
@@ -243,17 +438,25 @@ func createTable(conn *pgx.Conn, tableName, schema string) tea.Cmd {
 	}
 }
 
-func fetchTableData(conn *pgx.Conn, tableName string) tea.Cmd {
+func fetchTableData(conn *pgxpool.Pool, tableName string, filter db.RowFilter) tea.Cmd {
 	return func() tea.Msg {
-		data, err := db.GetTableData(conn, tableName)
+		data, err := db.GetTableData(conn, tableName, filter)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		columns, err := db.GetColumnInfo(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		foreignKeys, err := db.GetForeignKeys(conn, tableName)
 		if err != nil {
 			return errMsg{err: err}
 		}
-		return tableDataMsg{data: data}
+		return tableDataMsg{data: data, columns: columns, foreignKeys: foreignKeys}
 	}
 }
 
-func fetchTableColumns(conn *pgx.Conn, tableName string) tea.Cmd {
+func fetchTableColumns(conn *pgxpool.Pool, tableName string) tea.Cmd {
 	return func() tea.Msg {
 		columns, err := db.GetTableColumns(conn, tableName)
 		if err != nil {
@@ -263,7 +466,7 @@ func fetchTableColumns(conn *pgx.Conn, tableName string) tea.Cmd {
 	}
 }
 
-func insertRow(conn *pgx.Conn, tableName string, values map[string]interface{}) tea.Cmd {
+func insertRow(conn *pgxpool.Pool, tableName string, values map[string]interface{}) tea.Cmd {
 	return func() tea.Msg {
 		err := db.InsertRow(conn, tableName, values)
 		if err != nil {
@@ -274,6 +477,7 @@ func insertRow(conn *pgx.Conn, tableName string, values map[string]interface{})
 }
 
 func (m *Model) Init() tea.Cmd {
+	m.spinnerTicking = true
 	return tea.Batch(
 		m.spinner.Tick,
 		checkDbInstalled(),
@@ -288,47 +492,117 @@ func (m *Model) adjustListSizes() {
 	m.tableList.SetSize(listWidth, listHeight)
 	m.dataTable.SetWidth(listWidth)
 	m.dataTable.SetHeight(listHeight)
+	m.recordView.Width = listWidth
+	m.recordView.Height = listHeight
+	m.structureTable.SetWidth(listWidth)
+	m.structureTable.SetHeight(listHeight)
+	m.indexTable.SetWidth(listWidth)
+	m.indexTable.SetHeight(listHeight)
+	m.constraintTable.SetWidth(listWidth)
+	m.constraintTable.SetHeight(listHeight)
+	m.roleTable.SetWidth(listWidth)
+	m.roleTable.SetHeight(listHeight)
+	m.activityTable.SetWidth(listWidth - m.activityPaneWidth())
+	m.activityTable.SetHeight(listHeight - 2)
+	m.lockTable.SetWidth(listWidth)
+	m.lockTable.SetHeight(listHeight)
+	m.maintenanceLogView.Width = listWidth
+	m.statementTable.SetWidth(listWidth)
+	m.statementTable.SetHeight(listHeight - 2)
+	m.settingsList.SetSize(listWidth, listHeight-2)
+	m.functionTable.SetWidth(listWidth)
+	m.functionTable.SetHeight(listHeight - 2)
+	m.functionSource.Width = listWidth
+	m.functionSource.Height = listHeight
+	m.triggerTable.SetWidth(listWidth)
+	m.triggerTable.SetHeight(listHeight)
+	m.viewList.SetSize(listWidth, listHeight)
+	m.editDiffView.Width = listWidth
+	m.editDiffView.Height = listHeight
+	m.ddlView.Width = listWidth
+	m.ddlView.Height = listHeight
+	m.compareList.SetSize(listWidth, listHeight)
+	tableHeight, paneHeight := m.schemaDiffHeights()
+	m.schemaDiffTable.SetWidth(listWidth)
+	m.schemaDiffTable.SetHeight(tableHeight)
+	m.schemaFromView.Width = listWidth/2 - 3
+	m.schemaFromView.Height = paneHeight
+	m.schemaToView.Width = listWidth/2 - 3
+	m.schemaToView.Height = paneHeight
+	m.queryTable.SetWidth(listWidth)
+	m.queryTable.SetHeight(listHeight - 2)
+	m.extensionTable.SetWidth(listWidth)
+	m.extensionTable.SetHeight(listHeight)
+	m.statementView.Width = listWidth
+	m.statementView.Height = listHeight
+	m.maintenanceLogView.Height = max(listHeight-statsPaneHeight, 3)
+	m.privilegeTable.SetWidth(listWidth)
+	m.privilegeTable.SetHeight(listHeight)
+	m.privilegeObjectList.SetSize(listWidth, listHeight)
+	m.defaultPrivilegeTable.SetWidth(listWidth)
+	m.defaultPrivilegeTable.SetHeight(listHeight)
+	m.fkTableList.SetSize(listWidth, listHeight)
+	m.fkColumnList.SetSize(listWidth, listHeight)
+	m.referencedBy.SetSize(listWidth, listHeight)
+	m.refreshGrid()
 }
 
-func handleGlobalKeys(msg tea.KeyMsg) tea.Cmd {
+func (m *Model) handleGlobalKeys(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
-	case "ctrl+c", "q":
+	case "ctrl+c":
 		return tea.Quit
+	case "q":
+		// 'q' is just a letter while typing into a form
+		if !m.isEditingText() {
+			return tea.Quit
+		}
 	}
 	return nil
 }
 
-func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m *Model) isEditingText() bool {
+	switch m.state {
+	case StateEnterPassword, StateCreateTableName, StateCreateTableSchema, StateAddRow, StateForm, StateConfirmTableAction:
+		return true
+	case StateSettings:
+		return m.settingsList.FilterState() == list.Filtering
+	}
+	return false
+}
+
+// updateSession updates the active session.
+func (m *Model) updateSession(msg tea.Msg) tea.Cmd {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
-	// Handle window size messages
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.windowSize = msg
-		m.adjustListSizes()
-	}
-
 	// Handle global key presses
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
-		if cmd := handleGlobalKeys(keyMsg); cmd != nil {
-			return m, cmd
+		if cmd := m.handleGlobalKeys(keyMsg); cmd != nil {
+			return cmd
 		}
 	}
 
+	// Health checks and maintenance output are handled whatever the
+	// session is showing
+	switch msg := msg.(type) {
+	case healthCheckMsg:
+		return m.handleHealthCheck(msg)
+	case definitionMsg, editorClosedMsg:
+		return m.handleEditorMsg(msg)
+	case maintenanceLogMsg, maintenanceDoneMsg:
+		return m.handleMaintenanceMsg(msg)
+	}
+
 	switch m.state {
 	case StateLoading:
-		m.spinner, cmd = m.spinner.Update(msg)
-		cmds = append(cmds, cmd)
-
 		switch msg := msg.(type) {
 		case postgresFoundMsg:
 			m.state = StateSelectUser
-			conn, err := db.ConnectAsUser("postgres", "postgres", "postgres")
+			conn, err := db.ConnectAsUser("postgres", "postgres", "postgres", db.SessionSettings{})
 			if err != nil {
 				m.err = err
 				m.state = StateError
-				return m, nil
+				return nil
 			}
 			m.conn = conn
 			cmds = append(cmds, fetchUsers(conn))
@@ -348,7 +622,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.userList.SetItems(convertToListItems(msg.users))
 			cmds = append(cmds, fetchDatabases(m.conn))
 		case databasesMsg:
-			m.databaseList.SetItems(convertToListItems(msg.databases))
+			m.databases = msg.databases
+			m.databaseList.SetItems(databaseItems(msg.databases))
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "enter":
@@ -365,6 +640,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateSelectDatabase:
 		m.databaseList, cmd = m.databaseList.Update(msg)
 		cmds = append(cmds, cmd)
+		cmds = append(cmds, m.updateDatabaseActions(msg)...)
 
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -373,6 +649,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				selectedItem := m.databaseList.SelectedItem()
 				if selectedItem != nil {
 					m.selectedDB = selectedItem.(myListItem).title
+					// The password is kept when switching databases as the same user
+					if m.userPassword != "" {
+						m.state = StateConnecting
+						cmds = append(cmds, connectAsUser(m.selectedUser, m.userPassword, m.selectedDB))
+						break
+					}
 					m.state = StateEnterPassword
 					m.passwordInput.Focus()
 				}
@@ -399,17 +681,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.state = StateError
 		}
 	case StateConnecting:
-		m.spinner, cmd = m.spinner.Update(msg)
-		cmds = append(cmds, cmd)
-
 		switch msg := msg.(type) {
 		case connectedMsg:
 			m.dbConn = msg.conn
+			m.err = nil
 			m.state = StateListTables
-			cmds = append(cmds, fetchTables(m.dbConn))
+			cmds = append(cmds, fetchTables(m.dbConn), checkHealth(m.dbConn, healthCheckInterval))
 		case errMsg:
+			// Most likely a wrong or no longer valid password, so ask again
 			m.err = msg.err
-			m.state = StateError
+			m.userPassword = ""
+			m.state = StateEnterPassword
+			m.passwordInput.Focus()
 		}
 	case StateListTables:
 		m.tableList, cmd = m.tableList.Update(msg)
@@ -417,25 +700,110 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		switch msg := msg.(type) {
 		case tablesMsg:
+			m.tables = msg.tables
 			m.tableList.SetItems(convertToListItems(msg.tables))
 		case tableCreatedMsg:
 			cmds = append(cmds, fetchTables(m.dbConn))
 			m.state = StateListTables
+		case tableActionDoneMsg:
+			cmds = append(cmds, fetchTables(m.dbConn))
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "n":
 				m.initTableCreationInputs()
 				m.state = StateCreateTableName
-			case "enter":
+			case "s":
 				selectedItem := m.tableList.SelectedItem()
 				if selectedItem != nil {
 					m.selectedTable = selectedItem.(myListItem).title
-					cmds = append(cmds, fetchTableData(m.dbConn, m.selectedTable))
-					m.state = StateViewTable
+					m.structureColumns = nil
+					m.initStructureTable()
+					cmds = append(cmds, fetchTableStructure(m.dbConn, m.selectedTable))
+					m.state = StateTableStructure
+				}
+			case "i":
+				selectedItem := m.tableList.SelectedItem()
+				if selectedItem != nil {
+					m.selectedTable = selectedItem.(myListItem).title
+					m.indexes = nil
+					m.initIndexTable()
+					cmds = append(cmds, fetchIndexes(m.dbConn, m.selectedTable))
+					m.state = StateIndexes
+				}
+			case "x", "t", "R":
+				selectedItem := m.tableList.SelectedItem()
+				if selectedItem != nil {
+					actions := map[string]tableAction{"x": tableActionDrop, "t": tableActionTruncate, "R": tableActionRename}
+					cmds = append(cmds, m.openTableAction(actions[msg.String()], selectedItem.(myListItem).title))
+				}
+			case "c":
+				selectedItem := m.tableList.SelectedItem()
+				if selectedItem != nil {
+					m.selectedTable = selectedItem.(myListItem).title
+					m.constraints = nil
+					m.initConstraintTable()
+					cmds = append(cmds, fetchConstraints(m.dbConn, m.selectedTable))
+					m.state = StateConstraints
+				}
+			case "r":
+				m.roles = nil
+				m.initRoleTable()
+				cmds = append(cmds, fetchRoles(m.dbConn))
+				m.state = StateRoles
+			case "A":
+				cmds = append(cmds, m.openActivity())
+			case "L":
+				cmds = append(cmds, m.openLocks())
+			case "Q":
+				cmds = append(cmds, m.openTopQueries())
+			case "C":
+				cmds = append(cmds, m.openSettings())
+			case "E":
+				cmds = append(cmds, m.openExtensions())
+			case "F":
+				cmds = append(cmds, m.openFunctions())
+			case "V":
+				cmds = append(cmds, m.openViews())
+			case "e":
+				cmds = append(cmds, m.editQueryText())
+			case "Y":
+				if selectedItem := m.tableList.SelectedItem(); selectedItem != nil {
+					cmds = append(cmds, m.openTableDDL(selectedItem.(myListItem).title))
+				}
+			case "X":
+				m.openExportSchema()
+			case "M":
+				m.openCompare()
+			case "T":
+				if selectedItem := m.tableList.SelectedItem(); selectedItem != nil {
+					cmds = append(cmds, m.openTriggers(selectedItem.(myListItem).title))
+				}
+			case "S":
+				if selectedItem := m.tableList.SelectedItem(); selectedItem != nil {
+					cmds = append(cmds, m.openTableStats(selectedItem.(myListItem).title))
+				}
+			case "D":
+				cmds = append(cmds, m.switchDatabase())
+			case "U":
+				cmds = append(cmds, m.switchUser())
+			case "P":
+				// Start with the selected table, 'o' in the matrix picks another object
+				obj := db.PrivilegeObject{Kind: "database", Name: m.selectedDB}
+				if selectedItem := m.tableList.SelectedItem(); selectedItem != nil {
+					obj = db.PrivilegeObject{Kind: "table", Name: selectedItem.(myListItem).title}
+				}
+				cmds = append(cmds, m.openPrivileges(obj))
+			case "enter":
+				selectedItem := m.tableList.SelectedItem()
+				if selectedItem != nil {
+					m.navStack = nil
+					cmds = append(cmds, m.openTable(selectedItem.(myListItem).title, db.RowFilter{}))
 				}
 			}
 		case tableDataMsg:
 			m.tableData = msg.data
+			m.dataColumns = msg.columns
+			m.foreignKeys = msg.foreignKeys
 			m.initDataTable()
 		case errMsg:
 			m.err = msg.err
@@ -453,7 +821,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.tableName == "" {
 					m.err = fmt.Errorf("Table name cannot be empty")
 				} else {
-					m.initTableSchemaInput()
+					m.err = nil
+					m.initTableDesigner()
 					m.state = StateCreateTableSchema
 				}
 			case "esc":
@@ -463,23 +832,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg.err
 		}
 	case StateCreateTableSchema:
-		m.tableSchemaInput, cmd = m.tableSchemaInput.Update(msg)
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, m.updateTableDesigner(msg))
 
 		switch msg := msg.(type) {
-		case tea.KeyMsg:
-			switch msg.String() {
-			case "enter":
-				m.tableSchema = m.tableSchemaInput.Value()
-				if m.tableSchema == "" {
-					m.err = fmt.Errorf("Table schema cannot be empty")
-				} else {
-					cmds = append(cmds, createTable(m.dbConn, m.tableName, m.tableSchema))
-					m.state = StateListTables // Corrected state transition
-				}
-			case "esc":
-				m.state = StateListTables
-			}
 		case errMsg:
 			m.err = msg.err
 		case tableCreatedMsg:
@@ -495,20 +850,49 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "esc":
-				m.state = StateListTables
+				if len(m.navStack) > 0 {
+					cmds = append(cmds, m.goBack())
+				} else {
+					m.state = StateListTables
+				}
+			case "o":
+				cmds = append(cmds, m.followForeignKey())
+			case "r":
+				if len(m.tableData) > 0 {
+					cmds = append(cmds, fetchReferencingKeys(m.dbConn, m.selectedTable))
+				}
 			case "a":
 				cmds = append(cmds, fetchTableColumns(m.dbConn, m.selectedTable))
 				// Transition to StateAddRow happens after columns are fetched
+			case "enter":
+				if len(m.tableData) > 0 {
+					m.initRecordView(m.tableData[m.dataTable.Cursor()])
+					m.state = StateRecordDetail
+				}
+			case "left", "h":
+				m.moveColumnFocus(-1)
+			case "right", "l":
+				m.moveColumnFocus(1)
+			case "+", "=":
+				m.resizeFocusedColumn(2)
+			case "-":
+				m.resizeFocusedColumn(-2)
+			case "p":
+				m.togglePinnedColumn()
 			}
 		case tableColumnsMsg:
 			m.tableColumns = msg.columns
 			m.initAddRowInputs()
 			m.state = StateAddRow
+		case referencingKeysMsg:
+			m.openReferencedBy(msg.keys)
 		case rowInsertedMsg:
-			cmds = append(cmds, fetchTableData(m.dbConn, m.selectedTable))
+			cmds = append(cmds, fetchTableData(m.dbConn, m.selectedTable, m.rowFilter))
 			m.state = StateViewTable
 		case tableDataMsg:
 			m.tableData = msg.data
+			m.dataColumns = msg.columns
+			m.foreignKeys = msg.foreignKeys
 			m.initDataTable()
 		case errMsg:
 			m.err = msg.err
@@ -557,24 +941,106 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg.err
 			m.state = StateError
 		case rowInsertedMsg:
-			cmds = append(cmds, fetchTableData(m.dbConn, m.selectedTable))
+			cmds = append(cmds, fetchTableData(m.dbConn, m.selectedTable, m.rowFilter))
 			m.state = StateViewTable
 		case tableDataMsg:
 			m.tableData = msg.data
+			m.dataColumns = msg.columns
+			m.foreignKeys = msg.foreignKeys
 			m.initDataTable()
 		}
+	case StateReferencedBy:
+		cmds = append(cmds, m.updateReferencedBy(msg)...)
+	case StateConfirmTableAction:
+		cmds = append(cmds, m.updateTableAction(msg))
+	case StateRecordDetail:
+		m.recordView, cmd = m.recordView.Update(msg)
+		cmds = append(cmds, cmd)
+
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				m.state = StateViewTable
+			}
+		case tea.WindowSizeMsg:
+			// Re-wrap the values for the new width
+			m.initRecordView(m.tableData[m.dataTable.Cursor()])
+		}
+	case StateTableStructure:
+		cmds = append(cmds, m.updateTableStructure(msg)...)
+	case StateIndexes:
+		cmds = append(cmds, m.updateIndexes(msg)...)
+	case StateConstraints:
+		cmds = append(cmds, m.updateConstraints(msg)...)
+	case StateRoles:
+		cmds = append(cmds, m.updateRoles(msg)...)
+	case StateActivity:
+		cmds = append(cmds, m.updateActivity(msg)...)
+	case StateLocks:
+		cmds = append(cmds, m.updateLocks(msg)...)
+	case StateTableStats:
+		cmds = append(cmds, m.updateTableStats(msg)...)
+	case StateTopQueries:
+		cmds = append(cmds, m.updateTopQueries(msg)...)
+	case StateSettings:
+		cmds = append(cmds, m.updateSettings(msg)...)
+	case StateExtensions:
+		cmds = append(cmds, m.updateExtensions(msg)...)
+	case StateFunctions:
+		cmds = append(cmds, m.updateFunctions(msg)...)
+	case StateFunctionSource:
+		cmds = append(cmds, m.updateFunctionSource(msg)...)
+	case StateTriggers:
+		cmds = append(cmds, m.updateTriggers(msg)...)
+	case StateViews:
+		cmds = append(cmds, m.updateViews(msg)...)
+	case StateEditDiff:
+		cmds = append(cmds, m.updateEditDiff(msg)...)
+	case StateQueryResult:
+		cmds = append(cmds, m.updateQueryResult(msg)...)
+	case StateDDL:
+		cmds = append(cmds, m.updateDDL(msg)...)
+	case StateCompareTarget:
+		cmds = append(cmds, m.updateCompareTarget(msg)...)
+	case StateSchemaDiff:
+		cmds = append(cmds, m.updateSchemaDiff(msg)...)
+	case StateTopQueryDetail:
+		cmds = append(cmds, m.updateTopQueryDetail(msg)...)
+	case StatePrivileges:
+		cmds = append(cmds, m.updatePrivileges(msg)...)
+	case StatePrivilegeObjects:
+		cmds = append(cmds, m.updatePrivilegeObjects(msg)...)
+	case StateDefaultPrivileges:
+		cmds = append(cmds, m.updateDefaultPrivileges(msg)...)
+	case StateForeignKeyTable:
+		cmds = append(cmds, m.updateForeignKeyTable(msg)...)
+	case StateForeignKeyColumns:
+		cmds = append(cmds, m.updateForeignKeyColumns(msg)...)
+	case StateForm:
+		cmds = append(cmds, m.updateForm(msg))
+
+		switch msg := msg.(type) {
+		case errMsg:
+			m.err = msg.err
+		}
+	case StateConfirmDDL:
+		cmds = append(cmds, m.updateConfirmDDL(msg))
 	case StateError:
 		switch msg.(type) {
 		case tea.KeyMsg:
 			m.err = nil
-			// Return to the previous state or a safe state
-			m.state = StateListTables
+			// Without the maintenance connection there is nothing to go back to
+			if m.conn == nil {
+				return tea.Quit
+			}
+			m.state = m.recoveryState()
 		default:
-			return m, nil
+			return nil
 		}
 	}
 
-	return m, tea.Batch(cmds...)
+	return tea.Batch(cmds...)
 }
 
 func (m *Model) initTableCreationInputs() {
@@ -584,53 +1050,43 @@ func (m *Model) initTableCreationInputs() {
 	m.tableNameInput.Focus()
 }
 
-func (m *Model) initTableSchemaInput() {
-	m.tableSchemaInput = textinput.New()
-	m.tableSchemaInput.Placeholder = "id SERIAL PRIMARY KEY, name TEXT"
-	m.tableSchemaInput.Prompt = "Table Schema: "
-	m.tableSchemaInput.Focus()
-}
-
 func (m *Model) initDataTable() {
-	var columns []table.Column
-	var rows []table.Row
-
-	if len(m.tableData) == 0 {
-		// Fetch columns
-		columnsList, err := db.GetTableColumns(m.dbConn, m.selectedTable)
-		if err != nil {
-			m.err = err
-			m.state = StateError
-			return
-		}
-		for _, col := range columnsList {
-			columns = append(columns, table.Column{Title: col, Width: 20})
-		}
-	} else {
-		// Get columns from data
-		for col := range m.tableData[0] {
-			columns = append(columns, table.Column{Title: col, Width: 20})
+	// Format every cell once; columns come from the catalog so they keep
+	// their table order
+	m.gridCells = make([][]string, len(m.tableData))
+	for i, rowData := range m.tableData {
+		row := make([]string, len(m.dataColumns))
+		for j, col := range m.dataColumns {
+			row[j] = formatCell(col.Type, rowData[col.Name])
 		}
+		m.gridCells[i] = row
+	}
 
-		// Create rows
-		for _, rowData := range m.tableData {
-			row := table.Row{}
-			for _, col := range columns {
-				value := fmt.Sprintf("%v", rowData[col.Title])
-				row = append(row, value)
-			}
-			rows = append(rows, row)
-		}
+	// Keep the user's widths and pins when the same table is reloaded
+	if m.gridTable != m.selectedTable || len(m.columnWidths) != len(m.dataColumns) {
+		m.gridTable = m.selectedTable
+		m.initColumnLayout()
 	}
 
 	m.dataTable = table.New(
-		table.WithColumns(columns),
-		table.WithRows(rows),
 		table.WithFocused(true),
 		table.WithHeight(m.windowSize.Height-10),
 		table.WithWidth(m.windowSize.Width-4),
 	)
 	m.dataTable.SetStyles(tableStyle)
+
+	// Put the cursor back where it was when returning along the navigation
+	// stack, once the rows are there for it to point at
+	loc := m.restoreLocation
+	if loc != nil && loc.table == m.selectedTable {
+		m.restoreLocation = nil
+		m.gridFocus = min(loc.focus, max(len(m.dataColumns)-1, 0))
+		m.moveColumnFocus(0)
+	}
+	m.refreshGrid()
+	if loc != nil && loc.table == m.selectedTable {
+		m.dataTable.SetCursor(min(loc.cursor, max(len(m.gridCells)-1, 0)))
+	}
 }
 
 func (m *Model) initAddRowInputs() {
@@ -648,7 +1104,8 @@ func (m *Model) initAddRowInputs() {
 	m.currentInputIndex = 0
 }
 
-func (m *Model) View() string {
+// sessionView renders the active session.
+func (m *Model) sessionView() string {
 	var header string
 	// Corrected header construction
 	switch m.state {
@@ -664,6 +1121,10 @@ func (m *Model) View() string {
 		}
 	}
 
+	if m.reconnectAttempt > 0 {
+		header += " | " + reconnectingStyle.Render(fmt.Sprintf("Reconnecting (attempt %d)...", m.reconnectAttempt))
+	}
+
 	errorMsg := ""
 	if m.err != nil {
 		errorMsg = fmt.Sprintf("\n\nError: %v", m.err)
@@ -675,13 +1136,17 @@ func (m *Model) View() string {
 	case StateSelectUser:
 		return fmt.Sprintf("\n%s\n\n%s", header, m.userList.View())
 	case StateSelectDatabase:
-		return fmt.Sprintf("\n%s\n\n%s", header, m.databaseList.View())
+		instructions := "\n\nPress Enter to connect, 'n' to create a database, 'R' to rename it, 'x' to drop it."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.databaseList.View(), instructions, errorMsg)
 	case StateEnterPassword:
 		return fmt.Sprintf("\n%s\n\nEnter password for user '%s' on database '%s':\n\n%s%s", header, m.selectedUser, m.selectedDB, m.passwordInput.View(), errorMsg)
 	case StateConnecting:
 		return fmt.Sprintf("\n%s\n\n  %s Connecting to database...", header, m.spinner.View())
 	case StateListTables:
-		instructions := "\n\nPress 'n' to create a new table, 'q' to quit."
+		instructions := "\n\nPress 'e' to write a query in $EDITOR, 'n' to create a new table, 's' to view its structure, 'Y' for its DDL, 'i' for its indexes, 'c' for its constraints, 'T' for its triggers, 'S' for its statistics." +
+			"\nPress 'x' to drop, 't' to truncate, 'R' to rename the table, 'E' for extensions, 'F' for functions, 'V' for views, 'r' to manage roles, 'P' for privileges." +
+			"\nPress 'A' for activity, 'L' for locks, 'Q' for top queries, 'C' for server settings, 'X' to export a schema's DDL, 'M' to compare schemas, 'D' to switch database, 'U' to switch user, 'q' to quit." +
+			"\nCtrl+T opens a new tab, Ctrl+X closes it, Alt+1..9 or Ctrl+PgUp/PgDn switch tabs."
 		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.tableList.View(), instructions, errorMsg)
 	case StateCreateTableName:
 		return fmt.Sprintf(
@@ -691,19 +1156,25 @@ func (m *Model) View() string {
 			errorMsg,
 		)
 	case StateCreateTableSchema:
+		instructions := "\n\nTab/arrows to move, Space to toggle, ←/→ to pick a type or reference." +
+			"\nCtrl+N adds a column, Ctrl+D removes it, Ctrl+S creates the table, Esc cancels."
 		return fmt.Sprintf(
-			"\n%s\n\nCreate New Table\n\n%s%s\n\nPress Enter to create table, Esc to cancel.",
+			"\n%s\n\nCreate New Table: %s\n\n%s%s%s",
 			header,
-			m.tableSchemaInput.View(),
+			selectedStyle.Render(m.tableName),
+			m.tableDesignerView(),
 			errorMsg,
+			instructions,
 		)
 	case StateViewTable:
 		noDataMsg := ""
 		if len(m.tableData) == 0 {
 			noDataMsg = "\n\nNo data in this table."
 		}
-		instructions := "\n\nPress 'a' to add a new row, 'esc' to go back."
-		return fmt.Sprintf("\n%s\n\nViewing Table: %s%s%s\n\n%s", header, selectedStyle.Render(m.selectedTable), noDataMsg, instructions, m.dataTable.View())
+		instructions := "\n\nPress 'enter' to view a row, 'a' to add a new row, 'esc' to go back." +
+			"\nUse ←/→ to move between columns, '+'/'-' to resize, 'p' to pin." +
+			"\nPress 'o' to follow the foreign key under the cursor, 'r' for rows referencing this one."
+		return fmt.Sprintf("\n%s\n\nViewing Table: %s%s  %s%s%s%s\n\n%s", header, selectedStyle.Render(m.selectedTable), m.filterDescription(), m.gridStatus(), noDataMsg, instructions, errorMsg, styleNullCells(m.dataTable.View()))
 	case StateAddRow:
 		var inputsView strings.Builder
 		for i, input := range m.addRowInputs {
@@ -723,6 +1194,117 @@ func (m *Model) View() string {
 			instructions,
 			errorMsg,
 		)
+	case StateConfirmTableAction:
+		instructions := "\n\nTab to move, Space to toggle an option, Enter to confirm, Esc to cancel."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.tableActionView(), instructions, errorMsg)
+	case StateReferencedBy:
+		instructions := "\n\nPress Enter to open the referencing rows, Esc to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s", header, m.referencedBy.View(), instructions)
+	case StateRecordDetail:
+		instructions := "\n\nUse arrow keys to scroll, 'esc' to go back."
+		return fmt.Sprintf(
+			"\n%s\n\nRecord %d of %d in %s\n\n%s%s",
+			header,
+			m.dataTable.Cursor()+1,
+			len(m.tableData),
+			selectedStyle.Render(m.selectedTable),
+			m.recordView.View(),
+			instructions,
+		)
+	case StateTableStructure:
+		instructions := "\n\nColumn: 'a' add, 'x' drop, 'r' rename, 't' change type, 'n' toggle NOT NULL, 'e' default, 'c' comment." +
+			"\nTable: 'R' rename. Press 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nStructure of %s\n\n%s%s%s", header, selectedStyle.Render(m.selectedTable), m.structureTable.View(), instructions, errorMsg)
+	case StateIndexes:
+		instructions := "\n\nPress 'c' to create an index, 'x' to drop one, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nIndexes on %s\n\n%s%s%s", header, selectedStyle.Render(m.selectedTable), m.indexTable.View(), instructions, errorMsg)
+	case StateConstraints:
+		instructions := "\n\nPress 'F' to add a foreign key, 'c' to add a check, 'v' to validate, 'x' to drop, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nConstraints on %s\n\n%s%s%s", header, selectedStyle.Render(m.selectedTable), m.constraintTable.View(), instructions, errorMsg)
+	case StateRoles:
+		instructions := "\n\nPress 'n' to create a role, 'e' to edit its attributes, 'p' to change its password." +
+			"\nPress 'g' to grant membership in another role, 'v' to revoke it, 'x' to drop the role, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nRoles\n\n%s%s%s", header, m.roleTable.View(), instructions, errorMsg)
+	case StateActivity:
+		instructions := "\n\nPress 'c' to cancel the selected query, 'x' to terminate its backend." +
+			"\nPress 'i' to hide or show idle sessions, 's' to group by user or application, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nActivity\n\n%s%s%s", header, m.activityView(), instructions, errorMsg)
+	case StateTableStats:
+		instructions := "\n\nPress 'v' to VACUUM (ANALYZE, VERBOSE), 'a' to ANALYZE, 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nStatistics of %s\n\n%s\n%s%s%s", header, selectedStyle.Render(m.selectedTable), m.tableStatsView(), m.maintenanceLogView.View(), instructions, errorMsg)
+	case StateViews:
+		instructions := "\n\nPress Enter to edit the view in $EDITOR, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.viewList.View(), instructions, errorMsg)
+	case StateEditDiff:
+		instructions := "\n\nPress 'y' to apply the changes in a transaction, 'e' to edit again, 'esc' to discard them."
+		return fmt.Sprintf("\n%s\n\nChanges to %s\n\n%s%s%s", header, selectedStyle.Render(m.editTitle), m.editDiffView.View(), instructions, errorMsg)
+	case StateQueryResult:
+		instructions := "\n\nPress 'e' to edit the query, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.queryResultView(), instructions, errorMsg)
+	case StateDDL:
+		instructions := "\n\nUse arrow keys to scroll, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s\n\n%s%s%s", header, selectedStyle.Render(m.ddlTitle), m.ddlView.View(), instructions, errorMsg)
+	case StateCompareTarget:
+		instructions := "\n\nPress Enter to compare the public schemas, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.compareList.View(), instructions, errorMsg)
+	case StateSchemaDiff:
+		instructions := "\n\nPress 'J'/'K' to scroll the definitions, 'r' to reverse the direction, 'm' to show the migration script, 'w' to save it, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nSchema diff\n\n%s%s%s", header, m.schemaDiffView(), instructions, errorMsg)
+	case StateFunctions:
+		instructions := "\n\nPress Enter to view the source, 'e' to edit it in $EDITOR, 's' to show another schema, 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nFunctions\n\n%s%s%s", header, m.functionsView(), instructions, errorMsg)
+	case StateFunctionSource:
+		instructions := "\n\nUse arrow keys to scroll, 'e' to edit in $EDITOR, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s\n\n%s%s%s", header, selectedStyle.Render(m.functionTitle), m.functionSource.View(), instructions, errorMsg)
+	case StateTriggers:
+		noTriggersMsg := ""
+		if len(m.triggers) == 0 {
+			noTriggersMsg = "\n\nThe table has no triggers."
+		}
+		instructions := "\n\nPress Enter to enable or disable the trigger, 'o' to view its function, 'e' to edit it, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nTriggers on %s%s\n\n%s%s%s", header, selectedStyle.Render(m.selectedTable), noTriggersMsg, m.triggerTable.View(), instructions, errorMsg)
+	case StateExtensions:
+		instructions := "\n\nPress 'n' to create the selected extension, 'u' to update it, 'x' to drop it, 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nExtensions\n\n%s%s%s", header, m.extensionTable.View(), instructions, errorMsg)
+	case StateSettings:
+		instructions := "\n\nPress '/' to search, 'e' to ALTER SYSTEM SET the setting, 'x' to ALTER SYSTEM RESET it, 'L' to reload the configuration." +
+			"\nNon-default settings are highlighted. Press 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s%s", header, m.settingsView(), instructions, errorMsg)
+	case StateTopQueries:
+		instructions := "\n\nPress Enter to see a query in full, 's' to change the sort order, 'R' to reset the statistics, 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nTop Queries\n\n%s%s%s", header, m.topQueriesView(), instructions, errorMsg)
+	case StateTopQueryDetail:
+		instructions := "\n\nPress 'e' to EXPLAIN the query, ↑/↓ to scroll, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nQuery\n\n%s%s%s", header, m.statementView.View(), instructions, errorMsg)
+	case StateLocks:
+		noLocksMsg := ""
+		if len(m.lockRows) == 0 {
+			noLocksMsg = "\n\nNo backend is waiting for a lock."
+		}
+		instructions := "\n\nPress 'x' to terminate the backend at the root of the selected chain, 'r' to refresh, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nBlocking Chains%s\n\n%s%s%s", header, noLocksMsg, m.lockTable.View(), instructions, errorMsg)
+	case StatePrivileges:
+		instructions := "\n\n✓ granted, ✓* with grant option, ~ through membership, ownership or superuser." +
+			"\nUse ←/→ to pick a privilege, Enter to grant or revoke it, 'o' for another object, 'D' for default privileges, 'esc' to go back."
+		title := fmt.Sprintf("Privileges on %s %s", m.privilegeObject.Kind, selectedStyle.Render(m.privilegeObject.Name))
+		return fmt.Sprintf("\n%s\n\n%s\n\n%s%s%s", header, title, m.privilegeTable.View(), instructions, errorMsg)
+	case StatePrivilegeObjects:
+		instructions := "\n\nPress Enter to show the object's privileges, Esc to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s", header, m.privilegeObjectList.View(), instructions)
+	case StateDefaultPrivileges:
+		instructions := "\n\nPress 'c' to grant or revoke default privileges, 'x' to revoke the selected ones, 'esc' to go back."
+		return fmt.Sprintf("\n%s\n\nDefault Privileges\n\n%s%s%s", header, m.defaultPrivilegeTable.View(), instructions, errorMsg)
+	case StateForeignKeyTable:
+		instructions := "\n\nPress Enter to pick the referenced table, Esc to cancel."
+		return fmt.Sprintf("\n%s\n\n%s%s", header, m.fkTableList.View(), instructions)
+	case StateForeignKeyColumns:
+		instructions := "\n\nPress Space to toggle a column, Enter to continue, Esc to go back."
+		return fmt.Sprintf("\n%s\n\n%s%s", header, m.fkColumnList.View(), instructions)
+	case StateForm:
+		instructions := "\n\nPress Enter to proceed, Tab to navigate, Esc to cancel."
+		return fmt.Sprintf("\n%s\n\n%s\n\n%s%s%s", header, m.formTitle, m.formView(), instructions, errorMsg)
+	case StateConfirmDDL:
+		return fmt.Sprintf("\n%s\n\nThe following statement will be executed:\n\n%s\n\nPress 'y' to execute, 'n' to cancel.", header, selectedStyle.Render(m.pendingDDL))
 	case StateError:
 		return fmt.Sprintf("\nAn error occurred: %v\n\nPress any key to continue.", m.err)
 	default:
@@ -731,6 +1313,18 @@ func (m *Model) View() string {
 }
 
 func main() {
+	timezone := flag.String("timezone", "Local", "time zone for displaying timestamptz values, e.g. UTC or Europe/Berlin")
+	flag.StringVar(&sessionSettings.SearchPath, "search-path", "", "comma separated schemas to set as search_path on every connection, followed by public")
+	flag.StringVar(&sessionSettings.Role, "role", "", "role to SET ROLE to on every connection")
+	flag.StringVar(&sessionSettings.StatementTimeout, "statement-timeout", "", "statement_timeout for every connection, e.g. 30s")
+	flag.Parse()
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Fatalf("Invalid time zone %q: %v", *timezone, err)
+	}
+	displayLocation = loc
+
 	model := initializeModel()
 
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -740,14 +1334,12 @@ func main() {
 	}
 
 	if model.conn != nil {
-		if err := model.conn.Close(context.Background()); err != nil {
-			log.Printf("Error closing connection: %v", err)
-		}
+		model.conn.Close()
 	}
 
-	if model.dbConn != nil {
-		if err := model.dbConn.Close(context.Background()); err != nil {
-			log.Printf("Error closing database connection: %v", err)
-		}
-	}
-}
+	for _, s := range model.sessions {
+		if s.dbConn != nil {
+			s.dbConn.Close()
+		}
+	}
+}
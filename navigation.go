@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// gridLocation is an entry on the navigation stack: a table, the filter it
+// was opened with and where the cursor was.
+type gridLocation struct {
+	table  string
+	filter db.RowFilter
+	cursor int
+	focus  int
+}
+
+type referencingKeysMsg struct{ keys []db.ForeignKey }
+
+func fetchReferencingKeys(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		keys, err := db.GetReferencingForeignKeys(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return referencingKeysMsg{keys: keys}
+	}
+}
+
+// openTable shows a table's rows, optionally restricted by filter.
+func (m *Model) openTable(tableName string, filter db.RowFilter) tea.Cmd {
+	m.selectedTable = tableName
+	m.rowFilter = filter
+	m.err = nil
+	m.state = StateViewTable
+	return fetchTableData(m.dbConn, tableName, filter)
+}
+
+func (m *Model) pushLocation() {
+	m.navStack = append(m.navStack, gridLocation{
+		table:  m.selectedTable,
+		filter: m.rowFilter,
+		cursor: m.dataTable.Cursor(),
+		focus:  m.gridFocus,
+	})
+}
+
+// goBack returns to the table on top of the navigation stack.
+func (m *Model) goBack() tea.Cmd {
+	loc := m.navStack[len(m.navStack)-1]
+	m.navStack = m.navStack[:len(m.navStack)-1]
+	m.restoreLocation = &loc
+	return m.openTable(loc.table, loc.filter)
+}
+
+// rowValues returns the current row's values for the given columns, or
+// false if any of them is NULL and so references nothing.
+func (m *Model) rowValues(columns []string) ([]interface{}, bool) {
+	row := m.tableData[m.dataTable.Cursor()]
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		if row[col] == nil {
+			return nil, false
+		}
+		values[i] = row[col]
+	}
+	return values, true
+}
+
+func (m *Model) foreignKeyForColumn(column string) (db.ForeignKey, bool) {
+	for _, fk := range m.foreignKeys {
+		for _, col := range fk.Columns {
+			if col == column {
+				return fk, true
+			}
+		}
+	}
+	return db.ForeignKey{}, false
+}
+
+// followForeignKey opens the row referenced by the focused column.
+func (m *Model) followForeignKey() tea.Cmd {
+	if len(m.tableData) == 0 || len(m.dataColumns) == 0 {
+		return nil
+	}
+
+	column := m.dataColumns[m.gridFocus].Name
+	fk, ok := m.foreignKeyForColumn(column)
+	if !ok {
+		m.err = fmt.Errorf("%s is not a foreign key column", column)
+		return nil
+	}
+	values, ok := m.rowValues(fk.Columns)
+	if !ok {
+		m.err = fmt.Errorf("%s is NULL and references no row", column)
+		return nil
+	}
+
+	m.pushLocation()
+	return m.openTable(fk.RefTable, db.RowFilter{Columns: fk.RefColumns, Values: values})
+}
+
+func (m *Model) openReferencedBy(keys []db.ForeignKey) {
+	if len(keys) == 0 {
+		m.err = fmt.Errorf("no tables reference %s", m.selectedTable)
+		return
+	}
+
+	items := make([]string, len(keys))
+	for i, fk := range keys {
+		items[i] = fmt.Sprintf("%s (%s) → %s", fk.Table, strings.Join(fk.Columns, ", "), strings.Join(fk.RefColumns, ", "))
+	}
+	m.referencingKeys = keys
+	m.referencedBy = m.newPickerList("Referenced By", items)
+	m.err = nil
+	m.state = StateReferencedBy
+}
+
+func (m *Model) updateReferencedBy(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.referencedBy, cmd = m.referencedBy.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateViewTable
+		case "enter":
+			fk := m.referencingKeys[m.referencedBy.Index()]
+			values, ok := m.rowValues(fk.RefColumns)
+			if !ok {
+				m.err = fmt.Errorf("the referenced columns are NULL in this row")
+				m.state = StateViewTable
+				break
+			}
+			m.pushLocation()
+			cmds = append(cmds, m.openTable(fk.Table, db.RowFilter{Columns: fk.Columns, Values: values}))
+		}
+	}
+
+	return cmds
+}
+
+// filterDescription describes the active row filter, e.g.
+// " where customer_id = 42".
+func (m *Model) filterDescription() string {
+	if m.rowFilter.Empty() {
+		return ""
+	}
+	conditions := make([]string, len(m.rowFilter.Columns))
+	for i, col := range m.rowFilter.Columns {
+		conditions[i] = fmt.Sprintf("%s = %s", col, formatValue("", m.rowFilter.Values[i]))
+	}
+	return " where " + strings.Join(conditions, " and ")
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type privilegesMsg struct{ privileges []db.RolePrivileges }
+type privilegeObjectsMsg struct{ objects []db.PrivilegeObject }
+type defaultPrivilegesMsg struct{ defaults []db.DefaultPrivilege }
+
+func fetchPrivileges(conn *pgxpool.Pool, obj db.PrivilegeObject) tea.Cmd {
+	return func() tea.Msg {
+		privileges, err := db.GetPrivileges(conn, obj)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return privilegesMsg{privileges: privileges}
+	}
+}
+
+func fetchPrivilegeObjects(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		objects, err := db.GetPrivilegeObjects(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return privilegeObjectsMsg{objects: objects}
+	}
+}
+
+func fetchDefaultPrivileges(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		defaults, err := db.GetDefaultPrivileges(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return defaultPrivilegesMsg{defaults: defaults}
+	}
+}
+
+// openPrivileges shows the privilege matrix of obj.
+func (m *Model) openPrivileges(obj db.PrivilegeObject) tea.Cmd {
+	m.privilegeObject = obj
+	m.privileges = nil
+	m.privilegeFocus = 0
+	m.initPrivilegeTable()
+	m.err = nil
+	m.state = StatePrivileges
+	return fetchPrivileges(m.dbConn, obj)
+}
+
+// privilegeCell renders one cell of the matrix: ✓ for a direct grant, ✓*
+// when it may also be granted on, ~ when the role only has the privilege
+// through membership, ownership or superuser.
+func privilegeCell(rp db.RolePrivileges, privilege string) string {
+	switch {
+	case rp.GrantOption[privilege]:
+		return "✓*"
+	case rp.Granted[privilege]:
+		return "✓"
+	case rp.Effective[privilege]:
+		return "~"
+	}
+	return ""
+}
+
+func (m *Model) initPrivilegeTable() {
+	privileges := db.ObjectPrivileges[m.privilegeObject.Kind]
+	columns := []table.Column{{Title: "Role", Width: 24}}
+	for i, p := range privileges {
+		title := p
+		if i == m.privilegeFocus {
+			title = "›" + p
+		}
+		columns = append(columns, table.Column{Title: title, Width: 11})
+	}
+
+	rows := make([]table.Row, len(m.privileges))
+	for i, rp := range m.privileges {
+		row := table.Row{rp.Role}
+		for _, p := range privileges {
+			row = append(row, privilegeCell(rp, p))
+		}
+		rows[i] = row
+	}
+
+	cursor := m.privilegeTable.Cursor()
+	m.privilegeTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.privilegeTable.SetStyles(tableStyle)
+	m.privilegeTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+// togglePrivilege revokes the focused privilege if the role was granted it
+// directly and grants it otherwise, after showing the statement.
+func (m *Model) togglePrivilege() {
+	if len(m.privileges) == 0 {
+		return
+	}
+	rp := m.privileges[m.privilegeTable.Cursor()]
+	privilege := db.ObjectPrivileges[m.privilegeObject.Kind][m.privilegeFocus]
+	if rp.Granted[privilege] {
+		m.confirmDDL(db.RevokeDDL(m.privilegeObject, privilege, rp.Role), StatePrivileges)
+	} else {
+		m.confirmDDL(db.GrantDDL(m.privilegeObject, privilege, rp.Role), StatePrivileges)
+	}
+}
+
+// privilegeRoles lists the roles of the matrix, optionally with PUBLIC.
+func (m *Model) privilegeRoles(withPublic bool) []string {
+	var roles []string
+	for _, rp := range m.privileges {
+		if rp.Role != db.PublicRole || withPublic {
+			roles = append(roles, rp.Role)
+		}
+	}
+	return roles
+}
+
+func (m *Model) updatePrivileges(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.privilegeTable, cmd = m.privilegeTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case privilegesMsg:
+		m.privileges = msg.privileges
+		m.initPrivilegeTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchPrivileges(m.dbConn, m.privilegeObject))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "left", "h":
+			m.privilegeFocus = max(m.privilegeFocus-1, 0)
+			m.initPrivilegeTable()
+		case "right", "l":
+			m.privilegeFocus = min(m.privilegeFocus+1, len(db.ObjectPrivileges[m.privilegeObject.Kind])-1)
+			m.initPrivilegeTable()
+		case "enter":
+			m.togglePrivilege()
+		case "o":
+			cmds = append(cmds, fetchPrivilegeObjects(m.dbConn))
+			// Transition to StatePrivilegeObjects happens after objects are fetched
+		case "D":
+			m.defaultPrivileges = nil
+			m.initDefaultPrivilegeTable()
+			cmds = append(cmds, fetchDefaultPrivileges(m.dbConn))
+			m.state = StateDefaultPrivileges
+		}
+	case privilegeObjectsMsg:
+		m.privilegeObjects = msg.objects
+		items := make([]string, len(msg.objects))
+		for i, o := range msg.objects {
+			items[i] = fmt.Sprintf("%-9s %s", o.Kind, o.Name)
+		}
+		m.privilegeObjectList = m.newPickerList("Grant Privileges On", items)
+		m.state = StatePrivilegeObjects
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+func (m *Model) updatePrivilegeObjects(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.privilegeObjectList, cmd = m.privilegeObjectList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StatePrivileges
+		case "enter":
+			if len(m.privilegeObjects) > 0 {
+				cmds = append(cmds, m.openPrivileges(m.privilegeObjects[m.privilegeObjectList.Index()]))
+			}
+		}
+	}
+
+	return cmds
+}
+
+func (m *Model) initDefaultPrivilegeTable() {
+	columns := []table.Column{
+		{Title: "Owner", Width: 20},
+		{Title: "Schema", Width: 16},
+		{Title: "On", Width: 10},
+		{Title: "Grantee", Width: 20},
+		{Title: "Privileges", Width: max(m.windowSize.Width-4-66-10, 20)},
+	}
+
+	rows := make([]table.Row, len(m.defaultPrivileges))
+	for i, d := range m.defaultPrivileges {
+		schema := d.Schema
+		if schema == "" {
+			schema = "(all)"
+		}
+		rows[i] = table.Row{d.Owner, schema, d.ObjectType, d.Grantee, strings.Join(d.Privileges, ", ")}
+	}
+
+	cursor := m.defaultPrivilegeTable.Cursor()
+	m.defaultPrivilegeTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.defaultPrivilegeTable.SetStyles(tableStyle)
+	m.defaultPrivilegeTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateDefaultPrivileges(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.defaultPrivilegeTable, cmd = m.defaultPrivilegeTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case defaultPrivilegesMsg:
+		m.defaultPrivileges = msg.defaults
+		m.initDefaultPrivilegeTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchDefaultPrivileges(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StatePrivileges
+		case "c":
+			roles := m.privilegeRoles(false)
+			m.openForm(formDefaultPrivileges, "Alter Default Privileges", StateDefaultPrivileges,
+				formField{prompt: "For objects created by", options: roles, value: m.selectedUser},
+				formField{prompt: "In schema", placeholder: "optional, every schema when empty"},
+				formField{prompt: "Action", options: []string{"GRANT", "REVOKE"}},
+				formField{prompt: "Privileges", placeholder: "comma separated, or ALL"},
+				formField{prompt: "On", options: db.DefaultPrivilegeTypeNames},
+				formField{prompt: "Grantee", options: m.privilegeRoles(true)},
+			)
+		case "x":
+			if len(m.defaultPrivileges) == 0 {
+				break
+			}
+			d := m.defaultPrivileges[m.defaultPrivilegeTable.Cursor()]
+			ddl, err := db.AlterDefaultPrivilegesDDL(d.Owner, d.Schema, false, d.Privileges, d.ObjectType, d.Grantee)
+			if err != nil {
+				m.err = err
+				break
+			}
+			m.confirmDDL(ddl, StateDefaultPrivileges)
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
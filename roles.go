@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// noReassign is the drop form's choice for dropping a role without handing
+// its objects over to another role first.
+const noReassign = "(none)"
+
+type rolesMsg struct{ roles []db.Role }
+
+func fetchRoles(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		roles, err := db.GetRoles(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return rolesMsg{roles: roles}
+	}
+}
+
+func yesNoValue(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func (m *Model) initRoleTable() {
+	columns := []table.Column{
+		{Title: "Role", Width: 20},
+		{Title: "Super", Width: 5},
+		{Title: "Create DB", Width: 9},
+		{Title: "Create Role", Width: 11},
+		{Title: "Login", Width: 5},
+		{Title: "Repl", Width: 4},
+		{Title: "Conn Limit", Width: 10},
+		{Title: "Valid Until", Width: 22},
+		{Title: "Member Of", Width: max(m.windowSize.Width-4-86-18, 20)},
+	}
+
+	rows := make([]table.Row, len(m.roles))
+	for i, r := range m.roles {
+		connLimit := "∞"
+		if r.ConnectionLimit >= 0 {
+			connLimit = strconv.Itoa(r.ConnectionLimit)
+		}
+		rows[i] = table.Row{
+			r.Name,
+			yesNoValue(r.Superuser),
+			yesNoValue(r.CreateDB),
+			yesNoValue(r.CreateRole),
+			yesNoValue(r.Login),
+			yesNoValue(r.Replication),
+			connLimit,
+			r.ValidUntil,
+			strings.Join(r.MemberOf, ", "),
+		}
+	}
+
+	cursor := m.roleTable.Cursor()
+	m.roleTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.roleTable.SetStyles(tableStyle)
+	m.roleTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) selectedRole() db.Role {
+	return m.roles[m.roleTable.Cursor()]
+}
+
+// otherRoles lists every role except the selected one.
+func (m *Model) otherRoles() []string {
+	var names []string
+	for _, r := range m.roles {
+		if r.Name != m.selectedRole().Name {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// roleAttributeFields are the form fields for a role's attributes, filled
+// in from attrs.
+func roleAttributeFields(attrs db.RoleAttributes) []formField {
+	return []formField{
+		{prompt: "Superuser", options: yesNo, value: yesNoValue(attrs.Superuser)},
+		{prompt: "Create databases", options: yesNo, value: yesNoValue(attrs.CreateDB)},
+		{prompt: "Create roles", options: yesNo, value: yesNoValue(attrs.CreateRole)},
+		{prompt: "Login", options: yesNo, value: yesNoValue(attrs.Login)},
+		{prompt: "Replication", options: yesNo, value: yesNoValue(attrs.Replication)},
+		{prompt: "Connection limit", placeholder: "-1 for no limit", value: strconv.Itoa(attrs.ConnectionLimit)},
+		{prompt: "Valid until", placeholder: "optional, e.g. 2030-01-01", value: attrs.ValidUntil},
+	}
+}
+
+// formRoleAttributes reads the fields added by roleAttributeFields,
+// starting at form input first.
+func (m *Model) formRoleAttributes(first int) (db.RoleAttributes, error) {
+	connLimit, err := strconv.Atoi(m.formValue(first + 5))
+	if err != nil || connLimit < -1 {
+		return db.RoleAttributes{}, fmt.Errorf("connection limit must be a number, -1 for no limit")
+	}
+	return db.RoleAttributes{
+		Superuser:       m.formBool(first),
+		CreateDB:        m.formBool(first + 1),
+		CreateRole:      m.formBool(first + 2),
+		Login:           m.formBool(first + 3),
+		Replication:     m.formBool(first + 4),
+		ConnectionLimit: connLimit,
+		ValidUntil:      m.formValue(first + 6),
+	}, nil
+}
+
+func (m *Model) updateRoles(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.roleTable, cmd = m.roleTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case rolesMsg:
+		m.roles = msg.roles
+		m.initRoleTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchRoles(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "n":
+			fields := append([]formField{
+				{prompt: "Name"},
+				{prompt: "Password", placeholder: "optional", secret: true},
+			}, roleAttributeFields(db.RoleAttributes{Login: true, ConnectionLimit: -1})...)
+			m.openForm(formCreateRole, "Create Role", StateRoles, fields...)
+		}
+
+		if len(m.roles) == 0 {
+			break
+		}
+		role := m.selectedRole()
+		switch msg.String() {
+		case "e":
+			m.openForm(formAlterRole, fmt.Sprintf("Edit Role %s", role.Name), StateRoles, roleAttributeFields(role.RoleAttributes)...)
+		case "p":
+			m.openForm(formSetPassword, fmt.Sprintf("Change Password of %s", role.Name), StateRoles,
+				formField{prompt: "New password", secret: true},
+				formField{prompt: "Repeat password", secret: true},
+			)
+		case "g":
+			if others := m.otherRoles(); len(others) > 0 {
+				m.openForm(formGrantRole, fmt.Sprintf("Grant Membership to %s", role.Name), StateRoles,
+					formField{prompt: "Role", options: others},
+				)
+			}
+		case "v":
+			if len(role.MemberOf) > 0 {
+				m.openForm(formRevokeRole, fmt.Sprintf("Revoke Membership from %s", role.Name), StateRoles,
+					formField{prompt: "Role", options: role.MemberOf},
+				)
+			}
+		case "x":
+			m.openForm(formDropRole, fmt.Sprintf("Drop Role %s", role.Name), StateRoles,
+				formField{prompt: "Reassign owned objects to", options: append([]string{noReassign}, m.otherRoles()...)},
+			)
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
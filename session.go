@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sessionMsg carries the result of a command back to the session that
+// started it, which may no longer be the active tab.
+type sessionMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+var (
+	tabStyle       = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("245"))
+	activeTabStyle = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("36")).Bold(true).Underline(true)
+)
+
+// bubbleteaPkg is the package path of bubbletea's own messages, which are
+// meant for the program rather than a session.
+var bubbleteaPkg = reflect.TypeOf(tea.QuitMsg{}).PkgPath()
+
+// wrapCmd tags the messages cmd produces with a session ID.
+func wrapCmd(id int, cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg := cmd()
+		switch msg := msg.(type) {
+		case nil:
+			return nil
+		case tea.BatchMsg:
+			cmds := make(tea.BatchMsg, len(msg))
+			for i, c := range msg {
+				cmds[i] = wrapCmd(id, c)
+			}
+			return cmds
+		}
+		if t := reflect.TypeOf(msg); t.PkgPath() == bubbleteaPkg {
+			return msg
+		}
+		return sessionMsg{id: id, msg: msg}
+	}
+}
+
+func newSessionList(title string) list.Model {
+	l := list.New([]list.Item{}, customDelegate{}, 0, 0)
+	l.Title = title
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles = newListStyles()
+	return l
+}
+
+// openSession adds a tab in the given state and makes it the active one.
+// The user and database lists are copied from the current tab.
+func (m *Model) openSession(state State) {
+	passwordInput := textinput.New()
+	passwordInput.Placeholder = "Enter password"
+	passwordInput.EchoMode = textinput.EchoPassword
+
+	dataTable := table.New()
+	dataTable.SetStyles(tableStyle)
+
+	s := &Session{
+		id:                 m.nextSessionID,
+		state:              state,
+		userList:           newSessionList("Select User"),
+		databaseList:       newSessionList("Select Database"),
+		passwordInput:      passwordInput,
+		tableList:          newSessionList("Tables"),
+		dataTable:          dataTable,
+		recordView:         viewport.New(0, 0),
+		maintenanceLogView: viewport.New(0, 0),
+		statementView:      viewport.New(0, 0),
+		functionSource:     viewport.New(0, 0),
+		editDiffView:       viewport.New(0, 0),
+		ddlView:            viewport.New(0, 0),
+		schemaFromView:     viewport.New(0, 0),
+		schemaToView:       viewport.New(0, 0),
+	}
+	s.maintenanceCtx, s.cancelMaintenance = context.WithCancel(context.Background())
+	if m.Session != nil {
+		s.userList.SetItems(m.userList.Items())
+		s.databases = m.databases
+		s.databaseList.SetItems(databaseItems(m.databases))
+	}
+	m.nextSessionID++
+
+	m.sessions = append(m.sessions, s)
+	m.Session = s
+	if m.windowSize.Width > 0 {
+		m.adjustListSizes()
+	}
+}
+
+func (m *Model) sessionByID(id int) *Session {
+	for _, s := range m.sessions {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *Model) activeIndex() int {
+	for i, s := range m.sessions {
+		if s == m.Session {
+			return i
+		}
+	}
+	return 0
+}
+
+// closeSession closes the active tab and its connection. The last tab
+// stays open.
+func (m *Model) closeSession() {
+	if len(m.sessions) == 1 {
+		return
+	}
+	m.cancelMaintenance()
+	if m.dbConn != nil {
+		m.dbConn.Close()
+	}
+
+	i := m.activeIndex()
+	m.sessions = append(m.sessions[:i], m.sessions[i+1:]...)
+	m.Session = m.sessions[min(i, len(m.sessions)-1)]
+}
+
+// runInSession updates s with msg while it stands in for the active
+// session, and tags the commands it starts so their results return to s.
+func (m *Model) runInSession(s *Session, msg tea.Msg) tea.Cmd {
+	active := m.Session
+	m.Session = s
+	cmd := m.updateSession(msg)
+	m.Session = active
+	return wrapCmd(s.id, cmd)
+}
+
+// handleTabKeys opens, closes and switches tabs. Ctrl+T opens a tab at the
+// user list, Ctrl+X closes the current one, Alt+1 to Alt+9 and
+// Ctrl+PgUp/PgDown switch between them.
+func (m *Model) handleTabKeys(msg tea.KeyMsg) bool {
+	switch key := msg.String(); key {
+	case "ctrl+t":
+		if m.conn == nil {
+			return false
+		}
+		m.openSession(StateSelectUser)
+	case "ctrl+x":
+		m.closeSession()
+	case "ctrl+pgdown":
+		m.Session = m.sessions[(m.activeIndex()+1)%len(m.sessions)]
+	case "ctrl+pgup":
+		m.Session = m.sessions[(m.activeIndex()-1+len(m.sessions))%len(m.sessions)]
+	default:
+		n, err := strconv.Atoi(strings.TrimPrefix(key, "alt+"))
+		if !strings.HasPrefix(key, "alt+") || err != nil || n < 1 || n > len(m.sessions) {
+			return false
+		}
+		m.Session = m.sessions[n-1]
+	}
+	return true
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if tick, ok := msg.(spinner.TickMsg); ok {
+		return m, m.updateSpinner(tick)
+	}
+	cmd := m.routeMsg(msg)
+	if !m.spinnerTicking && m.anyLoading() {
+		m.spinnerTicking = true
+		cmd = tea.Batch(cmd, m.spinner.Tick)
+	}
+	return m, cmd
+}
+
+// updateSpinner animates the spinner all tabs share, for as long as any
+// of them is loading.
+func (m *Model) updateSpinner(tick spinner.TickMsg) tea.Cmd {
+	m.spinnerTicking = false
+	if !m.anyLoading() {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(tick)
+	m.spinnerTicking = cmd != nil
+	return cmd
+}
+
+func (m *Model) anyLoading() bool {
+	for _, s := range m.sessions {
+		if s.state == StateLoading || s.state == StateConnecting {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMsg hands msg to the session it belongs to: the one that started
+// the command for a sessionMsg, the active one otherwise.
+func (m *Model) routeMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case sessionMsg:
+		s := m.sessionByID(msg.id)
+		if s == nil {
+			// The tab was closed while the command ran
+			return nil
+		}
+		return m.runInSession(s, msg.msg)
+	case tea.WindowSizeMsg:
+		m.windowSize = msg
+		active := m.Session
+		for _, s := range m.sessions {
+			m.Session = s
+			m.adjustListSizes()
+		}
+		m.Session = active
+	case tea.KeyMsg:
+		if m.handleTabKeys(msg) {
+			return nil
+		}
+	}
+
+	return m.runInSession(m.Session, msg)
+}
+
+// tabBar lists the open tabs, once there is more than one.
+func (m *Model) tabBar() string {
+	if len(m.sessions) < 2 {
+		return ""
+	}
+
+	tabs := make([]string, len(m.sessions))
+	for i, s := range m.sessions {
+		label := "new session"
+		switch {
+		case s.selectedUser != "" && s.selectedDB != "":
+			label = s.selectedUser + "@" + s.selectedDB
+		case s.selectedUser != "":
+			label = s.selectedUser
+		}
+		if s.reconnectAttempt > 0 {
+			label += " (reconnecting)"
+		}
+		style := tabStyle
+		if s == m.Session {
+			style = activeTabStyle
+		}
+		tabs[i] = style.Render(fmt.Sprintf("%d %s", i+1, label))
+	}
+	return "\n" + strings.Join(tabs, "│")
+}
+
+func (m *Model) View() string {
+	return m.tabBar() + m.sessionView()
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestSpinnerKeepsTickingForLoadingTabs(t *testing.T) {
+	m := initializeModel()
+	m.Init()
+	m.openSession(StateSelectUser)
+	m.sessions[0].state = StateConnecting
+
+	// The idle tab is active, the spinner still runs for the other one
+	_, cmd := m.Update(m.spinner.Tick())
+	if cmd == nil || !m.spinnerTicking {
+		t.Fatal("the spinner stopped while a tab was connecting")
+	}
+
+	m.sessions[0].state = StateListTables
+	if _, cmd := m.Update(cmd()); cmd != nil || m.spinnerTicking {
+		t.Error("the spinner kept running with no tab loading")
+	}
+
+	m.sessions[1].state = StateConnecting
+	if _, cmd := m.Update(struct{}{}); cmd == nil || !m.spinnerTicking {
+		t.Error("the spinner didn't start again for a tab that started connecting")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var (
+	nonDefaultStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	pendingRestartStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+type settingsMsg struct {
+	settings  []db.Setting
+	superuser bool
+}
+
+type settingItem struct{ setting db.Setting }
+
+func (i settingItem) FilterValue() string { return i.setting.Name + " " + i.setting.Category }
+
+// settingDelegate lists the settings in columns. The category is only
+// printed where it changes, so the settings of a category read as a group.
+type settingDelegate struct{}
+
+func (d settingDelegate) Height() int                               { return 1 }
+func (d settingDelegate) Spacing() int                              { return 0 }
+func (d settingDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+
+func (d settingDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	i, ok := item.(settingItem)
+	if !ok {
+		return
+	}
+	s := i.setting
+
+	category := ""
+	items := m.VisibleItems()
+	if index%m.Paginator.PerPage == 0 || items[index-1].(settingItem).setting.Category != s.Category {
+		category = s.Category
+	}
+
+	cursor := " "
+	nameStyle := normalStyle
+	if !s.IsDefault() {
+		nameStyle = nonDefaultStyle
+	}
+	if index == m.Index() {
+		cursor = ">"
+		nameStyle = selectedStyle
+	}
+
+	value := s.Value
+	if s.Unit != "" {
+		value += " " + s.Unit
+	}
+	pending := ""
+	if s.PendingRestart {
+		pending = pendingRestartStyle.Render("restart pending")
+	}
+
+	fmt.Fprintf(w, "%s %s %s %s %s %s\n", cursor,
+		detailTypeStyle.Render(fitWidth(category, 32)),
+		nameStyle.Render(fitWidth(s.Name, 36)),
+		fitWidth(value, 24),
+		detailTypeStyle.Render(fitWidth(s.Source, 18)),
+		pending)
+}
+
+// fitWidth pads or truncates s to width cells.
+func fitWidth(s string, width int) string {
+	if lipgloss.Width(s) > width {
+		runes := []rune(s)
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-lipgloss.Width(s))
+}
+
+func fetchSettings(conn *pgxpool.Pool) tea.Cmd {
+	return func() tea.Msg {
+		settings, err := db.GetSettings(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		superuser, err := db.IsSuperuser(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return settingsMsg{settings: settings, superuser: superuser}
+	}
+}
+
+func (m *Model) openSettings() tea.Cmd {
+	l := list.New(nil, settingDelegate{}, m.windowSize.Width-4, m.windowSize.Height-12)
+	l.Title = "Server Settings"
+	l.SetShowStatusBar(false)
+	l.DisableQuitKeybindings()
+	// Keep matches in category order so the grouping survives a search
+	l.Filter = list.UnsortedFilter
+	l.Styles = newListStyles()
+	m.settingsList = l
+	m.err = nil
+	m.state = StateSettings
+	return fetchSettings(m.dbConn)
+}
+
+func (m *Model) selectedSetting() (db.Setting, bool) {
+	item, ok := m.settingsList.SelectedItem().(settingItem)
+	return item.setting, ok
+}
+
+// settingField offers the accepted values of bool and enum settings as a
+// picker.
+func settingField(s db.Setting) formField {
+	field := formField{prompt: s.Name, value: s.Value}
+	switch s.Type {
+	case "bool":
+		field.options = []string{"on", "off"}
+	case "enum":
+		field.options = s.EnumValues
+	}
+	if s.Unit != "" {
+		field.placeholder = "in " + s.Unit
+	}
+	return field
+}
+
+// requireSuperuser explains why the configuration can't be changed by a
+// role that isn't a superuser.
+func (m *Model) requireSuperuser() bool {
+	if !m.settingsSuperuser {
+		m.err = fmt.Errorf("changing the server configuration needs a superuser")
+	}
+	return m.settingsSuperuser
+}
+
+func (m *Model) updateSettings(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+
+	// While typing a filter every key belongs to the list, and esc clears
+	// an applied filter before it leaves the view
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.settingsList.FilterState() != list.Unfiltered {
+		if m.settingsList.FilterState() == list.Filtering || keyMsg.String() == "esc" {
+			m.settingsList, cmd = m.settingsList.Update(msg)
+			return append(cmds, cmd)
+		}
+	}
+
+	m.settingsList, cmd = m.settingsList.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case settingsMsg:
+		m.settingsSuperuser = msg.superuser
+		items := make([]list.Item, len(msg.settings))
+		for i, s := range msg.settings {
+			items[i] = settingItem{setting: s}
+		}
+		cmds = append(cmds, m.settingsList.SetItems(items))
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchSettings(m.dbConn))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchSettings(m.dbConn))
+		case "e":
+			if s, ok := m.selectedSetting(); ok && m.requireSuperuser() {
+				m.openForm(formAlterSystem, "ALTER SYSTEM SET "+s.Name, StateSettings, settingField(s))
+			}
+		case "x":
+			if s, ok := m.selectedSetting(); ok && m.requireSuperuser() {
+				m.confirmDDL(db.AlterSystemResetDDL(s.Name), StateSettings)
+			}
+		case "L":
+			if m.requireSuperuser() {
+				m.confirmDDL(db.ReloadConfSQL, StateSettings)
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+// settingsView shows the list and the details of the selected setting.
+func (m *Model) settingsView() string {
+	details := ""
+	if s, ok := m.selectedSetting(); ok {
+		details = fmt.Sprintf("%s  %s\n%s %s   %s %s   %s %s   %s %s",
+			detailNameStyle.Render(s.Name), s.Description,
+			detailNameStyle.Render("Context"), s.Context,
+			detailNameStyle.Render("Type"), s.Type,
+			detailNameStyle.Render("Reset value"), s.ResetValue,
+			detailNameStyle.Render("Boot value"), s.BootValue)
+	}
+	return m.settingsList.View() + "\n" + details
+}
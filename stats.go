@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"lazysql/db"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type tableStatsMsg struct{ stats db.TableStats }
+
+// A maintenance run streams its output as maintenanceLogMsg, each carrying
+// the channel to wait on for the next line, and ends with maintenanceDoneMsg.
+type maintenanceLogMsg struct {
+	line   string
+	events <-chan tea.Msg
+}
+type maintenanceDoneMsg struct {
+	table string
+	err   error
+}
+
+// statsPaneHeight is the number of lines the stats take above the log pane.
+const statsPaneHeight = 16
+
+func fetchTableStats(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		stats, err := db.GetTableStats(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return tableStatsMsg{stats: stats}
+	}
+}
+
+// runMaintenance runs sql until it finishes or ctx is cancelled, which
+// happens when the tab is closed and nothing reads the output any more.
+func runMaintenance(ctx context.Context, conn *pgxpool.Pool, tableName, sql string) tea.Cmd {
+	return func() tea.Msg {
+		events := make(chan tea.Msg)
+		send := func(msg tea.Msg) {
+			select {
+			case events <- msg:
+			case <-ctx.Done():
+			}
+		}
+		go func() {
+			err := db.ExecWithNotices(ctx, conn, sql, func(line string) {
+				send(maintenanceLogMsg{line: line, events: events})
+			})
+			send(maintenanceDoneMsg{table: tableName, err: err})
+		}()
+		return maintenanceLogMsg{line: sql, events: events}
+	}
+}
+
+func waitForMaintenance(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// handleMaintenanceMsg appends a run's output to the log, whatever view is
+// showing, so the run is never left blocked on a line nobody reads.
+func (m *Model) handleMaintenanceMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case maintenanceLogMsg:
+		m.appendMaintenanceLog(msg.line)
+		return waitForMaintenance(msg.events)
+	case maintenanceDoneMsg:
+		m.maintenanceRunning = false
+		if msg.err != nil {
+			m.appendMaintenanceLog("ERROR: " + msg.err.Error())
+			return nil
+		}
+		m.appendMaintenanceLog("Done.")
+		if m.state == StateTableStats && m.selectedTable == msg.table {
+			return fetchTableStats(m.dbConn, msg.table)
+		}
+	}
+	return nil
+}
+
+func (m *Model) appendMaintenanceLog(line string) {
+	m.maintenanceLog = append(m.maintenanceLog, line)
+	m.maintenanceLogView.SetContent(strings.Join(m.maintenanceLog, "\n"))
+	m.maintenanceLogView.GotoBottom()
+}
+
+func (m *Model) openTableStats(tableName string) tea.Cmd {
+	m.selectedTable = tableName
+	m.tableStats = nil
+	m.err = nil
+	m.state = StateTableStats
+	return fetchTableStats(m.dbConn, tableName)
+}
+
+func (m *Model) updateTableStats(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.maintenanceLogView, cmd = m.maintenanceLogView.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tableStatsMsg:
+		m.tableStats = &msg.stats
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchTableStats(m.dbConn, m.selectedTable))
+		case "v", "a":
+			if m.maintenanceRunning {
+				m.err = fmt.Errorf("wait for the running command to finish")
+				break
+			}
+			sql := db.VacuumSQL(m.selectedTable)
+			if msg.String() == "a" {
+				sql = db.AnalyzeSQL(m.selectedTable)
+			}
+			m.err = nil
+			m.maintenanceRunning = true
+			cmds = append(cmds, runMaintenance(m.maintenanceCtx, m.dbConn, m.selectedTable, sql))
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+// formatLastRun shows when maintenance last ran and how long ago.
+func formatLastRun(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.In(displayLocation).Format("2006-01-02 15:04:05"), formatDuration(time.Since(*t).Round(time.Second)))
+}
+
+func percent(part, total int64) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
+
+func (m *Model) tableStatsView() string {
+	s := m.tableStats
+	if s == nil {
+		return "Loading statistics..."
+	}
+
+	bloat := "unknown until the table is analyzed"
+	if s.BloatBytes != nil {
+		bloat = s.BloatSize
+	}
+
+	lines := [][2]string{
+		{"Total size", s.TotalSize},
+		{"Table / indexes / TOAST", fmt.Sprintf("%s / %s / %s", s.TableSize, s.IndexSize, s.ToastSize)},
+		{"Estimated bloat", bloat},
+		{"Live / dead tuples", fmt.Sprintf("%d / %d (%s dead)", s.LiveTuples, s.DeadTuples, percent(s.DeadTuples, s.LiveTuples+s.DeadTuples))},
+		{"Sequential / index scans", fmt.Sprintf("%d / %d (%s index)", s.SeqScans, s.IndexScans, percent(s.IndexScans, s.SeqScans+s.IndexScans))},
+		{"HOT updates", fmt.Sprintf("%d of %d (%s)", s.HotUpdates, s.Updates, percent(s.HotUpdates, s.Updates))},
+		{"Last vacuum", formatLastRun(s.LastVacuum)},
+		{"Last autovacuum", formatLastRun(s.LastAutovacuum)},
+		{"Last analyze", formatLastRun(s.LastAnalyze)},
+		{"Last autoanalyze", formatLastRun(s.LastAutoanalyze)},
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		// Padded before styling, the escape codes would count as width
+		b.WriteString(fmt.Sprintf("  %s %s\n", detailNameStyle.Render(fmt.Sprintf("%-26s", line[0])), line[1]))
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type tableStructureMsg struct{ columns []db.Column }
+
+func fetchTableStructure(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		columns, err := db.GetColumnInfo(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return tableStructureMsg{columns: columns}
+	}
+}
+
+func (m *Model) initStructureTable() {
+	columns := []table.Column{
+		{Title: "Column", Width: 24},
+		{Title: "Type", Width: 28},
+		{Title: "Nullable", Width: 8},
+		{Title: "Default", Width: 30},
+		{Title: "Comment", Width: 30},
+	}
+
+	rows := make([]table.Row, len(m.structureColumns))
+	for i, col := range m.structureColumns {
+		nullable := "YES"
+		if col.NotNull {
+			nullable = "NO"
+		}
+		name := col.Name
+		if col.PrimaryKey {
+			name = "*" + name
+		}
+		rows[i] = table.Row{name, col.Type, nullable, col.Default, col.Comment}
+	}
+
+	cursor := m.structureTable.Cursor()
+	m.structureTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.structureTable.SetStyles(tableStyle)
+	m.structureTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) selectedColumn() db.Column {
+	if len(m.structureColumns) == 0 {
+		return db.Column{}
+	}
+	return m.structureColumns[m.structureTable.Cursor()]
+}
+
+func (m *Model) updateTableStructure(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.structureTable, cmd = m.structureTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tableStructureMsg:
+		m.structureColumns = msg.columns
+		m.initStructureTable()
+	case ddlExecutedMsg:
+		if m.pendingTableRename != "" {
+			m.selectedTable = m.pendingTableRename
+			m.pendingTableRename = ""
+		}
+		cmds = append(cmds, fetchTableStructure(m.dbConn, m.selectedTable))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+			cmds = append(cmds, fetchTables(m.dbConn))
+		case "a":
+			m.openForm(formAddColumn, "Add Column", StateTableStructure,
+				formField{prompt: "Name"},
+				formField{prompt: "Type", placeholder: "text"},
+				formField{prompt: "Not null", options: yesNo},
+				formField{prompt: "Default", placeholder: "optional SQL expression"},
+			)
+		case "R":
+			m.openForm(formRenameTable, "Rename Table", StateTableStructure,
+				formField{prompt: "New name", value: m.selectedTable},
+			)
+		}
+
+		if len(m.structureColumns) == 0 {
+			break
+		}
+		col := m.selectedColumn()
+		switch msg.String() {
+		case "x":
+			m.confirmDDL(db.DropColumnDDL(m.selectedTable, col.Name, false), StateTableStructure)
+		case "r":
+			m.openForm(formRenameColumn, fmt.Sprintf("Rename Column %s", col.Name), StateTableStructure,
+				formField{prompt: "New name", value: col.Name},
+			)
+		case "t":
+			m.openForm(formAlterColumnType, fmt.Sprintf("Change Type of %s", col.Name), StateTableStructure,
+				formField{prompt: "Type", value: col.Type},
+				formField{prompt: "Using", placeholder: fmt.Sprintf("optional, e.g. %s::integer", col.Name)},
+			)
+		case "n":
+			m.confirmDDL(db.SetNotNullDDL(m.selectedTable, col.Name, !col.NotNull), StateTableStructure)
+		case "e":
+			m.openForm(formSetDefault, fmt.Sprintf("Default for %s", col.Name), StateTableStructure,
+				formField{prompt: "Default", placeholder: "leave empty to drop the default", value: col.Default},
+			)
+		case "c":
+			m.openForm(formSetComment, fmt.Sprintf("Comment on %s", col.Name), StateTableStructure,
+				formField{prompt: "Comment", placeholder: "leave empty to remove the comment", value: col.Comment},
+			)
+		}
+	case errMsg:
+		// A failed rename leaves the table name as it was
+		m.pendingTableRename = ""
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
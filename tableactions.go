@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// tableAction is a destructive operation on a whole table. Like deleting a
+// repository on GitHub, it only runs once the table name has been typed.
+type tableAction int
+
+const (
+	tableActionDrop tableAction = iota
+	tableActionTruncate
+	tableActionRename
+)
+
+type tableActionOption struct {
+	label   string
+	checked bool
+}
+
+type tableActionDoneMsg struct{}
+type dependentObjectsMsg struct{ objects []string }
+
+func fetchDependentObjects(conn *pgxpool.Pool, tableName string) tea.Cmd {
+	return func() tea.Msg {
+		objects, err := db.GetDependentObjects(conn, tableName)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return dependentObjectsMsg{objects: objects}
+	}
+}
+
+func dropTable(conn *pgxpool.Pool, tableName string, cascade bool) tea.Cmd {
+	return func() tea.Msg {
+		if err := db.DropTable(conn, tableName, cascade); err != nil {
+			return errMsg{err: err}
+		}
+		return tableActionDoneMsg{}
+	}
+}
+
+func truncateTable(conn *pgxpool.Pool, tableName string, restartIdentity, cascade bool) tea.Cmd {
+	return func() tea.Msg {
+		if err := db.TruncateTable(conn, tableName, restartIdentity, cascade); err != nil {
+			return errMsg{err: err}
+		}
+		return tableActionDoneMsg{}
+	}
+}
+
+func renameTable(conn *pgxpool.Pool, tableName, newName string) tea.Cmd {
+	return func() tea.Msg {
+		if err := db.RenameTable(conn, tableName, newName); err != nil {
+			return errMsg{err: err}
+		}
+		return tableActionDoneMsg{}
+	}
+}
+
+func (m *Model) openTableAction(action tableAction, tableName string) tea.Cmd {
+	m.tableAction = action
+	m.selectedTable = tableName
+	m.dependentObjects = nil
+	m.err = nil
+
+	m.tableActionConfirm = textinput.New()
+	m.tableActionConfirm.Prompt = "Type the table name to confirm: "
+	m.tableActionConfirm.Placeholder = tableName
+
+	m.tableActionNewName = textinput.New()
+	m.tableActionNewName.Prompt = "New name: "
+	m.tableActionNewName.SetValue(tableName)
+
+	var cmd tea.Cmd
+	switch action {
+	case tableActionDrop:
+		m.tableActionOptions = []tableActionOption{{label: "CASCADE (also drop dependent objects)"}}
+		cmd = fetchDependentObjects(m.dbConn, tableName)
+	case tableActionTruncate:
+		m.tableActionOptions = []tableActionOption{
+			{label: "RESTART IDENTITY (reset owned sequences)"},
+			{label: "CASCADE (also truncate tables referencing this one)"},
+		}
+	case tableActionRename:
+		m.tableActionOptions = nil
+	}
+
+	// Focus order: the new name (rename only), each option, then the
+	// confirmation input, which is where the cursor starts.
+	m.tableActionFocus = m.tableActionFieldCount() - 1
+	if action == tableActionRename {
+		m.tableActionFocus = 0
+	}
+	m.focusTableActionField()
+	m.state = StateConfirmTableAction
+	return cmd
+}
+
+func (m *Model) tableActionFieldCount() int {
+	n := len(m.tableActionOptions) + 1
+	if m.tableAction == tableActionRename {
+		n++
+	}
+	return n
+}
+
+// focusedTableActionOption returns the index of the option under the
+// cursor, or -1.
+func (m *Model) focusedTableActionOption() int {
+	i := m.tableActionFocus
+	if m.tableAction == tableActionRename {
+		i--
+	}
+	if i >= 0 && i < len(m.tableActionOptions) {
+		return i
+	}
+	return -1
+}
+
+func (m *Model) focusTableActionField() {
+	m.tableActionNewName.Blur()
+	m.tableActionConfirm.Blur()
+	switch {
+	case m.tableActionFocus == m.tableActionFieldCount()-1:
+		m.tableActionConfirm.Focus()
+	case m.tableAction == tableActionRename && m.tableActionFocus == 0:
+		m.tableActionNewName.Focus()
+	}
+}
+
+func (m *Model) tableActionDDL() (string, error) {
+	switch m.tableAction {
+	case tableActionDrop:
+		return db.DropTableDDL(m.selectedTable, m.tableActionOptions[0].checked), nil
+	case tableActionTruncate:
+		return db.TruncateTableDDL(m.selectedTable, m.tableActionOptions[0].checked, m.tableActionOptions[1].checked), nil
+	default:
+		return db.RenameTableDDL(m.selectedTable, strings.TrimSpace(m.tableActionNewName.Value()))
+	}
+}
+
+func (m *Model) updateTableAction(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case dependentObjectsMsg:
+		m.dependentObjects = msg.objects
+		return nil
+	case errMsg:
+		m.err = msg.err
+		return nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.err = nil
+			m.state = StateListTables
+			return nil
+		case "tab", "down":
+			m.tableActionFocus = (m.tableActionFocus + 1) % m.tableActionFieldCount()
+			m.focusTableActionField()
+			return nil
+		case "shift+tab", "up":
+			m.tableActionFocus = (m.tableActionFocus - 1 + m.tableActionFieldCount()) % m.tableActionFieldCount()
+			m.focusTableActionField()
+			return nil
+		case " ":
+			if i := m.focusedTableActionOption(); i >= 0 {
+				m.tableActionOptions[i].checked = !m.tableActionOptions[i].checked
+				return nil
+			}
+		case "enter":
+			if m.tableActionConfirm.Value() != m.selectedTable {
+				m.err = fmt.Errorf("type %q to confirm", m.selectedTable)
+				return nil
+			}
+			if _, err := m.tableActionDDL(); err != nil {
+				m.err = err
+				return nil
+			}
+			m.err = nil
+			m.state = StateListTables
+			switch m.tableAction {
+			case tableActionDrop:
+				return dropTable(m.dbConn, m.selectedTable, m.tableActionOptions[0].checked)
+			case tableActionTruncate:
+				return truncateTable(m.dbConn, m.selectedTable, m.tableActionOptions[0].checked, m.tableActionOptions[1].checked)
+			default:
+				return renameTable(m.dbConn, m.selectedTable, strings.TrimSpace(m.tableActionNewName.Value()))
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	if m.tableActionNewName.Focused() {
+		m.tableActionNewName, cmd = m.tableActionNewName.Update(msg)
+	} else if m.tableActionConfirm.Focused() {
+		m.tableActionConfirm, cmd = m.tableActionConfirm.Update(msg)
+	}
+	return cmd
+}
+
+func (m *Model) tableActionView() string {
+	var b strings.Builder
+
+	titles := map[tableAction]string{
+		tableActionDrop:     "Drop Table",
+		tableActionTruncate: "Truncate Table",
+		tableActionRename:   "Rename Table",
+	}
+	b.WriteString(fmt.Sprintf("%s %s\n\n", titles[m.tableAction], selectedStyle.Render(m.selectedTable)))
+
+	if m.tableAction == tableActionDrop {
+		if len(m.dependentObjects) == 0 {
+			b.WriteString("No other objects depend on this table.\n\n")
+		} else {
+			b.WriteString("Objects depending on this table:\n")
+			for _, object := range m.dependentObjects {
+				b.WriteString("  - " + object + "\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	field := 0
+	line := func(s string) {
+		if field == m.tableActionFocus {
+			s = selectedStyle.Render(s)
+		}
+		b.WriteString(s + "\n")
+		field++
+	}
+
+	if m.tableAction == tableActionRename {
+		line(m.tableActionNewName.View())
+	}
+	for _, option := range m.tableActionOptions {
+		line(checkbox(option.checked) + " " + option.label)
+	}
+
+	if ddl, err := m.tableActionDDL(); err == nil {
+		b.WriteString("\n" + detailTypeStyle.Render(ddl) + "\n\n")
+	} else {
+		b.WriteString("\n")
+	}
+	line(m.tableActionConfirm.View())
+
+	return b.String()
+}
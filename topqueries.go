@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"lazysql/db"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var statementSortNames = map[db.StatementSort]string{
+	db.SortByTotalTime:  "total time",
+	db.SortByMeanTime:   "mean time",
+	db.SortByCalls:      "calls",
+	db.SortByRows:       "rows",
+	db.SortBySharedHit:  "shared hits",
+	db.SortBySharedRead: "shared reads",
+}
+
+// statementStatsMsg carries the statistics, or why there are none: schema
+// is empty when the extension isn't installed, and notLoaded is set when it
+// is installed but not preloaded.
+type statementStatsMsg struct {
+	schema    string
+	notLoaded bool
+	stats     []db.StatementStat
+}
+type explainMsg struct{ plan []string }
+
+func fetchStatementStats(conn *pgxpool.Pool, sort db.StatementSort) tea.Cmd {
+	return func() tea.Msg {
+		schema, err := db.StatStatementsSchema(conn)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		if schema == "" {
+			return statementStatsMsg{}
+		}
+		stats, err := db.GetStatementStats(conn, schema, sort)
+		if errors.Is(err, db.ErrStatStatementsNotLoaded) {
+			return statementStatsMsg{schema: schema, notLoaded: true}
+		}
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return statementStatsMsg{schema: schema, stats: stats}
+	}
+}
+
+func explainQuery(conn *pgxpool.Pool, query string) tea.Cmd {
+	return func() tea.Msg {
+		plan, err := db.Explain(conn, query)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return explainMsg{plan: plan}
+	}
+}
+
+func (m *Model) openTopQueries() tea.Cmd {
+	m.statementStats = nil
+	m.statStatementsMissing = false
+	m.statStatementsNotLoaded = false
+	m.initStatementTable()
+	m.err = nil
+	m.state = StateTopQueries
+	return fetchStatementStats(m.dbConn, m.statementSort)
+}
+
+// formatMillis shows sub-millisecond times with some precision, which
+// formatDuration rounds away.
+func formatMillis(ms float64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%.2fms", ms)
+	}
+	return formatDuration(time.Duration(ms * float64(time.Millisecond)))
+}
+
+func (m *Model) initStatementTable() {
+	columns := []table.Column{
+		{Title: "Calls", Width: 10},
+		{Title: "Total", Width: 10},
+		{Title: "Mean", Width: 10},
+		{Title: "Rows", Width: 10},
+		{Title: "Hits", Width: 10},
+		{Title: "Reads", Width: 10},
+		{Title: "Hit %", Width: 6},
+		{Title: "Query", Width: max(m.windowSize.Width-4-66-16, 20)},
+	}
+
+	rows := make([]table.Row, len(m.statementStats))
+	for i, s := range m.statementStats {
+		rows[i] = table.Row{
+			fmt.Sprintf("%d", s.Calls),
+			formatMillis(s.TotalTime),
+			formatMillis(s.MeanTime),
+			fmt.Sprintf("%d", s.Rows),
+			fmt.Sprintf("%d", s.SharedHit),
+			fmt.Sprintf("%d", s.SharedRead),
+			percent(s.SharedHit, s.SharedHit+s.SharedRead),
+			strings.Join(strings.Fields(s.Query), " "),
+		}
+	}
+
+	cursor := m.statementTable.Cursor()
+	m.statementTable = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(m.windowSize.Height-10),
+		table.WithWidth(m.windowSize.Width-4),
+	)
+	m.statementTable.SetStyles(tableStyle)
+	m.statementTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+}
+
+func (m *Model) updateTopQueries(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.statementTable, cmd = m.statementTable.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case statementStatsMsg:
+		m.statStatementsSchema = msg.schema
+		m.statStatementsMissing = msg.schema == ""
+		m.statStatementsNotLoaded = msg.notLoaded
+		m.statementStats = msg.stats
+		m.initStatementTable()
+	case ddlExecutedMsg:
+		cmds = append(cmds, fetchStatementStats(m.dbConn, m.statementSort))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateListTables
+		case "r":
+			cmds = append(cmds, fetchStatementStats(m.dbConn, m.statementSort))
+		case "s":
+			// The server only returns the top statements, so a new order
+			// needs a new fetch rather than a re-sort
+			m.statementSort = (m.statementSort + 1) % db.StatementSort(len(statementSortNames))
+			cmds = append(cmds, fetchStatementStats(m.dbConn, m.statementSort))
+		case "c":
+			if m.statStatementsMissing {
+				m.confirmDDL(db.CreateStatStatementsSQL, StateTopQueries)
+			}
+		case "R":
+			if !m.statStatementsMissing && !m.statStatementsNotLoaded {
+				m.confirmDDL(db.ResetStatStatementsSQL(m.statStatementsSchema), StateTopQueries)
+			}
+		case "enter":
+			if len(m.statementStats) > 0 {
+				m.selectedStatement = m.statementStats[m.statementTable.Cursor()]
+				m.statementPlan = nil
+				m.initStatementView()
+				m.state = StateTopQueryDetail
+			}
+		}
+	case errMsg:
+		m.err = msg.err
+		m.state = StateError
+	}
+
+	return cmds
+}
+
+// initStatementView shows the full query text and, once explained, its plan.
+func (m *Model) initStatementView() {
+	width := m.statementView.Width
+	if width <= 0 {
+		width = 80
+	}
+	s := m.selectedStatement
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s %d    %s %s    %s %s    %s %d\n\n",
+		detailNameStyle.Render("Calls"), s.Calls,
+		detailNameStyle.Render("Total"), formatMillis(s.TotalTime),
+		detailNameStyle.Render("Mean"), formatMillis(s.MeanTime),
+		detailNameStyle.Render("Rows"), s.Rows))
+	b.WriteString(lipgloss.NewStyle().Width(width).Render(s.Query))
+	if m.statementPlan != nil {
+		b.WriteString("\n\n" + detailNameStyle.Render("Plan") + "\n")
+		b.WriteString(strings.Join(m.statementPlan, "\n"))
+	}
+
+	m.statementView.SetContent(b.String())
+}
+
+// explainStatement runs EXPLAIN on the selected query, asking for sample
+// values first if it has parameters.
+func (m *Model) explainStatement() tea.Cmd {
+	count := db.CountParams(m.selectedStatement.Query)
+	if count == 0 {
+		return explainQuery(m.dbConn, m.selectedStatement.Query)
+	}
+
+	fields := make([]formField, count)
+	for i := range fields {
+		fields[i] = formField{prompt: fmt.Sprintf("$%d", i+1), placeholder: "empty for NULL"}
+	}
+	m.openForm(formExplainQuery, "Sample parameters for EXPLAIN", StateTopQueryDetail, fields...)
+	return nil
+}
+
+func (m *Model) explainWithFormParams() tea.Cmd {
+	params := make([]string, len(m.formInputs))
+	for i := range m.formInputs {
+		params[i] = m.formValue(i)
+	}
+	m.state = StateTopQueryDetail
+	return explainQuery(m.dbConn, db.BindSampleParams(m.selectedStatement.Query, params))
+}
+
+func (m *Model) updateTopQueryDetail(msg tea.Msg) []tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	m.statementView, cmd = m.statementView.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case explainMsg:
+		m.statementPlan = msg.plan
+		m.initStatementView()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateTopQueries
+		case "e":
+			cmds = append(cmds, m.explainStatement())
+		}
+	case errMsg:
+		// A query that can't be explained, such as a utility statement,
+		// shouldn't leave the view
+		m.err = msg.err
+	}
+
+	return cmds
+}
+
+func (m *Model) topQueriesView() string {
+	if m.statStatementsMissing {
+		return "The pg_stat_statements extension is not installed in this database." +
+			"\nPress 'c' to run CREATE EXTENSION pg_stat_statements. The server also needs it in shared_preload_libraries."
+	}
+	if m.statStatementsNotLoaded {
+		return "The pg_stat_statements extension is installed, but the server wasn't started with it loaded." +
+			"\nAdd pg_stat_statements to shared_preload_libraries and restart the server."
+	}
+	return fmt.Sprintf("%d queries, sorted by %s\n\n%s", len(m.statementStats), statementSortNames[m.statementSort], m.statementTable.View())
+}